@@ -0,0 +1,150 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/cmd/gelc/main.go
+
+// The GEL compiler.  At the moment it only has the `tokens` command, for
+// printing the tokens that the lexer scans from the input files (or stdin):
+//
+//	gelc tokens [--json] [--trivia] [--dialect kif|hrf|gel] [file ...]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SymbolNotFound/ggdl/pkg/lexer"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "tokens":
+		os.Exit(tokens(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "gelc: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gelc tokens [--json] [--trivia] [--dialect kif|hrf|gel] [file ...]")
+}
+
+// Prints the tokens of each file (or of stdin, if there are no files), as text
+// or as the canonical JSON of lexer.WriteTokensJSON.  Diagnostics are printed
+// to stderr, or included in the JSON.  Returns the exit code: 1 if any input
+// had an error diagnostic or could not be read, 2 for invalid arguments.
+func tokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the tokens as JSON")
+	trivia := flags.Bool("trivia", false, "attach spaces and comments to the tokens as trivia")
+	dialectName := flags.String("dialect", "",
+		"the dialect of the input (default: by file extension, GEL for stdin)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	var dialect *lexer.Dialect
+	if *dialectName != "" {
+		parsed, err := lexer.ParseDialect(*dialectName)
+		if err != nil {
+			fmt.Fprintln(stderr, "gelc:", err)
+			return 2
+		}
+		dialect = &parsed
+	}
+
+	status := 0
+	output := bufio.NewWriter(stdout)
+	defer output.Flush()
+	scan := func(name string, input io.Reader) {
+		opts := lexer.Options{Dialect: dialectFor(name, dialect), Trivia: *trivia}
+		scanner := lexer.NewScanner(bufio.NewReader(input), opts)
+		var tokens []lexer.Token
+		for token, err := range scanner.All() {
+			if err != nil {
+				fmt.Fprintf(stderr, "gelc: %s: %v\n", name, err)
+				status = 1
+				return
+			}
+			tokens = append(tokens, token)
+		}
+		for _, diag := range scanner.Diagnostics() {
+			if diag.Severity == lexer.SEVERITY_ERROR {
+				status = 1
+			}
+		}
+
+		if *asJSON {
+			err := lexer.WriteTokensJSON(output, filepath.Base(name), opts.Dialect,
+				tokens, scanner.Diagnostics())
+			if err != nil {
+				fmt.Fprintf(stderr, "gelc: %s: %v\n", name, err)
+				status = 1
+			}
+			return
+		}
+		for _, token := range tokens {
+			fmt.Fprintf(output, "%d:%d\t%s\t%q\n",
+				token.Line(), token.Column(), token.TypeString(), token.Image())
+		}
+		output.Flush()
+		for _, diag := range scanner.Diagnostics() {
+			fmt.Fprintf(stderr, "%s:%d:%d: %s: %s\n",
+				name, diag.Line(), diag.Column(), diag.Severity, diag.Message)
+		}
+	}
+
+	if flags.NArg() == 0 {
+		scan("<stdin>", stdin)
+	}
+	for _, name := range flags.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, "gelc:", err)
+			status = 1
+			continue
+		}
+		scan(name, file)
+		file.Close()
+	}
+	return status
+}
+
+// Chooses the dialect from the flag if it was given, otherwise from the file's
+// extension, defaulting to GEL.
+func dialectFor(name string, dialect *lexer.Dialect) lexer.Dialect {
+	if dialect != nil {
+		return *dialect
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".kif", ".gdl":
+		return lexer.KIF
+	case ".hrf":
+		return lexer.HRF
+	}
+	return lexer.GEL
+}
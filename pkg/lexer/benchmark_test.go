@@ -0,0 +1,164 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/benchmark_test.go
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// The lexer scans bytes in place and interns the TokenTypes of names, so once a
+// name has been seen (by this scanner or, through the pooled tables, an earlier
+// one) its tokens do not allocate.  Each scanner still allocates its own state,
+// and comments allocate their images, so scanning is not free of allocations:
+//
+//	BenchmarkScanBytes_PlayMessage   4 allocs/op, 752 B/op (a scanner and All())
+//	BenchmarkScan_PlayMessage        6 allocs/op, 832 B/op (and a read buffer
+//	                                 sized to the message)
+//	BenchmarkScanBytes_Rulesheet    84 allocs/op (2 for each of its 40 comments)
+//
+// TestScan_PlayMessageAllocs keeps the PLAY message cases from regressing.
+//
+// The lexer used to read its input through a Cursor, one rune at a time.  The
+// Cursor benchmarks below read the same inputs the way it was used, producing an
+// image for each run of non-space runes, for comparison with the Input ones that
+// do the same with the input the lexer now uses.  They only compare reading the
+// input, not scanning tokens, which the Cursor-based lexer no longer exists to do.
+
+// A PLAY message, as sent by the GM to each player on every turn of a match.
+const playMessage = "(PLAY tictactoe_0113 ((mark 2 3) noop))"
+
+// Part of the rules of tic-tac-toe in KIF, repeated to the size of a rulesheet.
+var rulesheet = strings.Repeat(`
+(role xplayer) (role oplayer)
+(init (cell 1 1 b)) (init (cell 1 2 b)) (init (control xplayer))
+(<= (next (cell ?m ?n x)) (does xplayer (mark ?m ?n)) (true (cell ?m ?n b)))
+(<= (next (cell ?m ?n o)) (does oplayer (mark ?m ?n)) (true (cell ?m ?n b)))
+(<= (row ?m ?x) (true (cell ?m 1 ?x)) (true (cell ?m 2 ?x)) (true (cell ?m 3 ?x)))
+(<= (legal ?w (mark ?x ?y)) (true (cell ?x ?y b)) (true (control ?w)))
+(<= (goal xplayer 100) (line x)) ; xplayer wins
+(<= terminal (not open))
+`, 40)
+
+func benchmarkScan(b *testing.B, input string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for range b.N {
+		scanner := NewScanner(strings.NewReader(input), Options{})
+		for _, err := range scanner.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Set when built with the race detector, see race_test.go.
+var raceEnabled bool
+
+func TestScan_PlayMessageAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations vary with the race detector")
+	}
+	scan := func(scanner TokenScanner) {
+		for _, err := range scanner.All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	data := []byte(playMessage)
+	if allocs := testing.AllocsPerRun(100, func() {
+		scan(NewBytesScanner(data, Options{}))
+	}); allocs > 4 {
+		t.Errorf("scanning a PLAY message in memory allocates %v times, want 4", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		scan(NewScanner(strings.NewReader(playMessage), Options{}))
+	}); allocs > 6 {
+		t.Errorf("scanning a PLAY message from a reader allocates %v times, want 6", allocs)
+	}
+}
+
+func BenchmarkScan_PlayMessage(b *testing.B) { benchmarkScan(b, playMessage) }
+func BenchmarkScan_Rulesheet(b *testing.B)   { benchmarkScan(b, rulesheet) }
+
+// Scanning a message already in memory, as the GM's messages are received.
+func benchmarkScanBytes(b *testing.B, input string) {
+	data := []byte(input)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		scanner := NewBytesScanner(data, Options{})
+		for _, err := range scanner.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkScanBytes_PlayMessage(b *testing.B) { benchmarkScanBytes(b, playMessage) }
+func BenchmarkScanBytes_Rulesheet(b *testing.B)   { benchmarkScanBytes(b, rulesheet) }
+
+func benchmarkCursor(b *testing.B, input string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for range b.N {
+		reader := strings.NewReader(input)
+		cursor := NewCursor()
+		for {
+			var r rune
+			cursor, r = cursor.FirstRune(reader)
+			if cursor.HasError() {
+				break
+			}
+			for !cursor.HasError() && !unicode.IsSpace(r) {
+				cursor, r = cursor.NextRune(reader)
+			}
+			if cursor.HasError() {
+				cursor, _ = cursor.ConsumeAll()
+			} else {
+				cursor, _ = cursor.ConsumeExceptFinal()
+			}
+		}
+	}
+}
+
+func BenchmarkCursor_PlayMessage(b *testing.B) { benchmarkCursor(b, playMessage) }
+func BenchmarkCursor_Rulesheet(b *testing.B)   { benchmarkCursor(b, rulesheet) }
+
+func benchmarkInput(b *testing.B, input string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for range b.N {
+		in := newInput(strings.NewReader(input), Options{})
+		for {
+			in.skipWhile(unicode.IsSpace)
+			if _, size := in.peek(); size == 0 {
+				break
+			}
+			in.begin()
+			in.skipWhile(func(r rune) bool { return !unicode.IsSpace(r) })
+			_ = string(in.image())
+		}
+	}
+}
+
+func BenchmarkInput_PlayMessage(b *testing.B) { benchmarkInput(b, playMessage) }
+func BenchmarkInput_Rulesheet(b *testing.B)   { benchmarkInput(b, rulesheet) }
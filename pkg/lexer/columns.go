@@ -0,0 +1,119 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/columns.go
+
+package lexer
+
+import "unicode/utf8"
+
+// The unit that columns are counted in, for the TokenPos of tokens and for
+// converting columns between clients.  Editors following the Language Server
+// Protocol (and JavaScript strings) count UTF-16 code units, Go tooling counts
+// bytes, and the default counts runes with tabs aligned to the tab width.
+type ColumnEncoding int
+
+const (
+	// Each rune is one column, except that a tab advances to the next tab stop.
+	// This is not the display width: a wide (East Asian) rune is one column, as
+	// is a zero-width or combining rune, so the columns of text with them do not
+	// line up with what a terminal shows.
+	COLUMNS_RUNES ColumnEncoding = iota
+	// Each byte of the UTF-8 encoding is one column, including a tab's.
+	COLUMNS_BYTES
+	// Each UTF-16 code unit is one column, so runes beyond the Basic
+	// Multilingual Plane are two columns, and a tab is one.  An invalid byte is
+	// one column, as its replacement character is.
+	COLUMNS_UTF16
+)
+
+func (encoding ColumnEncoding) String() string {
+	switch encoding {
+	case COLUMNS_RUNES:
+		return "runes"
+	case COLUMNS_BYTES:
+		return "bytes"
+	case COLUMNS_UTF16:
+		return "utf-16"
+	}
+	return "UNKNOWN"
+}
+
+// The default distance between tab stops, for columns counted in runes.
+const CURSOR_TAB_STOP = 4
+
+// Columns are counted in an encoding, with a tab width for rune columns.
+// The zero value counts runes with tab stops every CURSOR_TAB_STOP columns.
+type columnCounter struct {
+	encoding ColumnEncoding
+	tabWidth uint
+}
+
+func newColumnCounter(encoding ColumnEncoding, tabWidth int) columnCounter {
+	if tabWidth < 1 {
+		tabWidth = CURSOR_TAB_STOP
+	}
+	return columnCounter{encoding, uint(tabWidth)}
+}
+
+// Returns the (1-indexed) column after a rune of `size` bytes at column `col`.
+// The rune is not a newline, which begins the next line at column 1 instead.
+func (counter columnCounter) after(col uint, r rune, size int) uint {
+	switch {
+	case r == '\t' && counter.encoding == COLUMNS_RUNES:
+		return col + counter.tabWidth - (col-1)%counter.tabWidth
+	case size == 1:
+		return col + 1
+	case counter.encoding == COLUMNS_BYTES:
+		return col + uint(size)
+	case counter.encoding == COLUMNS_UTF16 && r >= 0x10000:
+		return col + 2
+	}
+	return col + 1
+}
+
+// Returns the (1-indexed) column of the byte offset within a line of text, in
+// the encoding, with tab stops every tabWidth columns for rune columns.
+func (encoding ColumnEncoding) Column(line []byte, offset int, tabWidth int) int {
+	counter := newColumnCounter(encoding, tabWidth)
+	col := uint(1)
+	for i := 0; i < min(offset, len(line)); {
+		r, size := utf8.DecodeRune(line[i:])
+		col = counter.after(col, r, size)
+		i += size
+	}
+	return int(col)
+}
+
+// Returns the byte offset within a line of text of a (1-indexed) column in the
+// encoding.  A column within a rune (or within the space of a tab) is the offset
+// of that rune, and a column beyond the end of the line is the line's length.
+func (encoding ColumnEncoding) Offset(line []byte, column int, tabWidth int) int {
+	counter := newColumnCounter(encoding, tabWidth)
+	col := uint(1)
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		col = counter.after(col, r, size)
+		if int(col) > column {
+			return i
+		}
+		i += size
+	}
+	return len(line)
+}
+
+// Converts a column within a line of text from one encoding to another.
+func ConvertColumn(line []byte, column int, from, to ColumnEncoding, tabWidth int) int {
+	return to.Column(line, from.Offset(line, column, tabWidth), tabWidth)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/columns_test.go
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestColumnEncoding_Column(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		offset    int
+		tabWidth  int
+		wantRunes int
+		wantBytes int
+		wantUTF16 int
+	}{
+		{"start", "(cell 1 1)", 0, 0, 1, 1, 1},
+		{"ascii", "(cell 1 1)", 6, 0, 7, 7, 7},
+		{"multibyte", "gøød x", 6, 0, 5, 7, 5},
+		{"astral", "🎲 x", 5, 0, 3, 6, 4},
+		// Rune columns are not display widths, wide and zero-width runes are one.
+		{"combining", "é x", 4, 0, 4, 5, 4},
+		{"wide", "\u6f22\u5b57 x", 7, 0, 4, 8, 4},
+		{"zero width", "a\u200bb x", 6, 0, 5, 7, 5},
+		{"tab", "\tx", 1, 0, 5, 2, 2},
+		{"tab width", "\tx", 1, 8, 9, 2, 2},
+		{"tab after text", "ab\tx", 3, 0, 5, 4, 4},
+		{"invalid", "a\xffb", 2, 0, 3, 3, 3},
+		{"beyond the line", "ab", 10, 0, 3, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := []byte(tt.line)
+			for encoding, want := range map[ColumnEncoding]int{
+				COLUMNS_RUNES: tt.wantRunes,
+				COLUMNS_BYTES: tt.wantBytes,
+				COLUMNS_UTF16: tt.wantUTF16,
+			} {
+				if got := encoding.Column(line, tt.offset, tt.tabWidth); got != want {
+					t.Errorf("%s Column() = %d, want %d", encoding, got, want)
+				}
+				if tt.offset <= len(line) {
+					if got := encoding.Offset(line, want, tt.tabWidth); got != tt.offset {
+						t.Errorf("%s Offset(%d) = %d, want %d", encoding, want, got, tt.offset)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConvertColumn(t *testing.T) {
+	line := []byte("\t(mark 🎲 gø)")
+	tests := []struct {
+		column   int
+		from, to ColumnEncoding
+		want     int
+	}{
+		{5, COLUMNS_RUNES, COLUMNS_UTF16, 2},
+		{11, COLUMNS_RUNES, COLUMNS_BYTES, 8},
+		{13, COLUMNS_UTF16, COLUMNS_RUNES, 15},
+		{12, COLUMNS_BYTES, COLUMNS_UTF16, 10},
+		// Within the space of the tab and within a rune, they are the same column.
+		{3, COLUMNS_RUNES, COLUMNS_BYTES, 1},
+		{9, COLUMNS_UTF16, COLUMNS_RUNES, 11},
+	}
+	for _, tt := range tests {
+		if got := ConvertColumn(line, tt.column, tt.from, tt.to, 0); got != tt.want {
+			t.Errorf("ConvertColumn(%d, %s, %s) = %d, want %d",
+				tt.column, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestScan_ColumnEncodings(t *testing.T) {
+	input := "(name \"gøød\" 🎲)\n\t(x)"
+	tests := []struct {
+		opts Options
+		want []uint
+	}{
+		{Options{}, []uint{1, 2, 7, 14, 15, 5, 6, 7}},
+		{Options{TabWidth: 2}, []uint{1, 2, 7, 14, 15, 3, 4, 5}},
+		{Options{Columns: COLUMNS_BYTES}, []uint{1, 2, 7, 16, 20, 2, 3, 4}},
+		{Options{Columns: COLUMNS_UTF16}, []uint{1, 2, 7, 14, 16, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s tab %d", tt.opts.Columns, tt.opts.TabWidth), func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(input), tt.opts)
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			if len(tokens) != len(tt.want) {
+				t.Fatalf("ScanAll() = %v", tokens)
+			}
+			for i, token := range tokens {
+				if token.Column() != tt.want[i] {
+					t.Errorf("%v column = %d, want %d", token, token.Column(), tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/comments.go
+
+package lexer
+
+import "strings"
+
+// Reads a block comment after its (consumed) opening marker up to and including
+// the closing marker, which may be on a later line.  If input ends first, the
+// partial comment is returned as an UnexpectedToken and a Diagnostic recorded.
+// The closing marker is only matched after the opening one, so that `(*)` does
+// not close itself.
+func (reader *lexerState) readBlockComment(marker blockCommentMarker, pos TokenPos) Token {
+	for !reader.lookingAt(marker.close) {
+		r, size := reader.peek()
+		if size == 0 {
+			image := string(reader.image())
+			reader.errorAt(pos, CODE_UNTERMINATED_COMMENT, "unterminated block comment")
+			return UnexpectedToken(image, pos)
+		}
+		reader.advance(r, size)
+	}
+	reader.consume(len(marker.close))
+	return BlockComment(string(reader.image()), pos)
+}
+
+// Block comments have the same COMMENT type as line comments, only the image
+// (and the number of lines it spans) is different.
+func BlockComment(image string, pos TokenPos) Token {
+	return Token{TokenPos: pos, TokenType: &comment{image}}
+}
+
+// A doc comment is a line of the block of line comments that immediately
+// precedes a declaration (with no blank line between them), such as:
+//
+//	%% The traditional ordering of available hand gestures:
+//	%% Paper covers rock, scissors cuts paper, rock breaks scissors.
+//	data Hand := ROCK << PAPER << SCISSORS << ROCK
+//
+// Each line of the block is a DOC_COMMENT token, with the same flags as other
+// comments.  When scanning with Options.Trivia, the lines are instead the
+// TRIVIA_DOC_COMMENT pieces in the leading trivia of the declaration.
+type DocCommentToken struct {
+	image  string
+	marker string
+}
+
+func DocComment(image string, pos TokenPos) Token {
+	return Token{TokenPos: pos, TokenType: &DocCommentToken{image, dialects[GEL].docMarker}}
+}
+func (doc *DocCommentToken) TypeString() string { return "DOC_COMMENT" }
+func (doc *DocCommentToken) Image() string      { return doc.image }
+
+// Returns the documentation text of the line, without its comment marker and
+// the space following it.
+func (doc *DocCommentToken) Text() string {
+	text := strings.TrimPrefix(doc.image, doc.marker)
+	return strings.TrimSuffix(strings.TrimPrefix(text, " "), "\r")
+}
+
+// Returns true if the token is a line comment that could begin a doc comment,
+// i.e. it begins with the doc marker and is the first token on its line.
+func (reader *lexerState) startsDocComment(token Token) bool {
+	marker := reader.dialect.docMarker
+	return marker != "" && isCommentToken(token) &&
+		strings.HasPrefix(token.Image(), marker) &&
+		token.Line() > reader.lastLine
+}
+
+// Reads the block of comments on the lines following the first, and the token
+// after them.  If that token is a declaration on the next line, the comments
+// are converted to doc comments.  The tokens after the first (and the error, if
+// one was returned by scanning) are queued to be returned by Scan().
+func (reader *lexerState) readDocComment(first Token) Token {
+	block := []Token{first}
+	for {
+		token, err := reader.scanToken()
+		last := block[len(block)-1]
+		adjacent := err == nil && token.Line() == last.Line()+1
+		if adjacent && isCommentToken(token) &&
+			strings.HasPrefix(token.Image(), reader.dialect.docMarker) {
+			block = append(block, token)
+			continue
+		}
+		if _, ok := token.TokenType.(KeywordToken); ok && adjacent &&
+			reader.dialect.declarations[token.Image()] {
+			for i, line := range block {
+				block[i].TokenType = &DocCommentToken{line.Image(), reader.dialect.docMarker}
+			}
+		}
+		for _, line := range block[1:] {
+			reader.queue = append(reader.queue, scanned{line, nil, true})
+		}
+		reader.queue = append(reader.queue, scanned{token, err, false})
+		return block[0]
+	}
+}
+
+// Marks the comments immediately before a declaration, in its leading trivia,
+// as doc comments.  These are the comments which are each followed by a single
+// newline (and optionally indentation) before the next comment or declaration.
+func (reader *lexerState) markDocTrivia(token Token) {
+	if _, ok := token.TokenType.(KeywordToken); !ok ||
+		!reader.dialect.declarations[token.Image()] {
+		return
+	}
+	pieces := token.Trivia.Leading
+	i := len(pieces) - 1
+	if i >= 0 && pieces[i].Kind == TRIVIA_SPACE {
+		i -= 1
+	}
+	for i >= 1 && pieces[i].Kind == TRIVIA_NEWLINE &&
+		pieces[i-1].Kind == TRIVIA_COMMENT &&
+		strings.HasPrefix(pieces[i-1].Text, reader.dialect.docMarker) {
+		pieces[i-1].Kind = TRIVIA_DOC_COMMENT
+		i -= 2
+		if i >= 0 && pieces[i].Kind == TRIVIA_SPACE {
+			i -= 1
+		}
+	}
+}
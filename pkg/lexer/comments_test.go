@@ -0,0 +1,145 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/comments_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_BlockComments(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   Dialect
+		input     string
+		want      []string
+		wantCodes []string
+	}{
+		{"gel", GEL, "a (* one\n two *) b", []string{
+			"IDENT:a", "COMMENT:(* one\n two *)", "IDENT:b"}, nil},
+		{"not self-closing", GEL, "(*) x *)", []string{"COMMENT:(*) x *)"}, nil},
+		{"not nested", GEL, "(* (* x *) *)", []string{
+			"COMMENT:(* (* x *)", "UNEXPECTED:*", "CLOSE_PAREN:)"},
+			[]string{CODE_UNKNOWN_OPERATOR, CODE_UNBALANCED_PAREN}},
+		{"expression", GEL, "(a)", []string{
+			"OPEN_PAREN:(", "IDENT:a", "CLOSE_PAREN:)"}, nil},
+		{"hrf", HRF, "% line\n(* block *) role(x)", []string{
+			"COMMENT:% line", "COMMENT:(* block *)", "KEYWORD:role",
+			"OPEN_PAREN:(", "IDENT:x", "CLOSE_PAREN:)"}, nil},
+		{"not in kif", KIF, "(* x)", []string{
+			"OPEN_PAREN:(", "UNEXPECTED:*", "IDENT:x", "CLOSE_PAREN:)"},
+			[]string{CODE_UNKNOWN_OPERATOR}},
+		{"unterminated", GEL, "a (* b", []string{"IDENT:a", "UNEXPECTED:(* b"},
+			[]string{CODE_UNTERMINATED_COMMENT}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{Dialect: tt.dialect})
+			got := []string{}
+			for token, err := range scanner.All() {
+				if err != nil {
+					t.Fatalf("All() yielded error %v", err)
+				}
+				got = append(got, token.TypeString()+":"+token.Image())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("All()\n  got %q\n want %q", got, tt.want)
+			}
+			var codes []string
+			for _, diag := range scanner.Diagnostics() {
+				codes = append(codes, diag.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("Diagnostics() codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+const docCommentInput = `%% Janken, a.k.a. Rock-Paper-Scissors.
+%%
+%% The header is not a doc comment, it is followed by a blank line.
+
+%% Roles may be defined together,
+  %% and indented.
+role left, right
+
+%% Not a doc comment, followed by a blank line.
+
+data Hand := ROCK
+x %% trailing
+%% Documents the initial state.
+init y
+%% Rules are not declarations.
+terminal :- y
+`
+
+func TestScan_DocComments(t *testing.T) {
+	tokens, err := ScanAll(strings.NewReader(docCommentInput), Options{Dialect: GEL})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	got := []string{}
+	docs := []string{}
+	for _, token := range tokens {
+		if !IsComment(token.TokenPos) {
+			continue
+		}
+		got = append(got, token.TypeString())
+		if doc, ok := token.TokenType.(*DocCommentToken); ok {
+			docs = append(docs, doc.Text())
+		}
+	}
+	want := []string{"COMMENT", "COMMENT", "COMMENT", "DOC_COMMENT", "DOC_COMMENT",
+		"COMMENT", "COMMENT", "DOC_COMMENT", "COMMENT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanAll() comment types\n  got %v\n want %v", got, want)
+	}
+	wantDocs := []string{"Roles may be defined together,", "and indented.",
+		"Documents the initial state."}
+	if !reflect.DeepEqual(docs, wantDocs) {
+		t.Errorf("DocCommentToken.Text()\n  got %q\n want %q", docs, wantDocs)
+	}
+}
+
+func TestScan_DocCommentTrivia(t *testing.T) {
+	tokens, err := ScanAll(strings.NewReader(docCommentInput),
+		Options{Dialect: GEL, Trivia: true})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	var text strings.Builder
+	got := []string{}
+	for _, token := range tokens {
+		text.WriteString(token.FullText())
+		for _, piece := range token.Trivia.Leading {
+			if piece.Kind == TRIVIA_COMMENT || piece.Kind == TRIVIA_DOC_COMMENT {
+				got = append(got, piece.Kind.String())
+			}
+		}
+	}
+	if text.String() != docCommentInput {
+		t.Errorf("FullText() of tokens = %q, want %q", text.String(), docCommentInput)
+	}
+	// The trailing comment is not in any leading trivia.
+	want := []string{"COMMENT", "COMMENT", "COMMENT", "DOC_COMMENT", "DOC_COMMENT",
+		"COMMENT", "DOC_COMMENT", "COMMENT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("leading trivia comments\n  got %v\n want %v", got, want)
+	}
+}
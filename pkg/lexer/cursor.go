@@ -46,6 +46,10 @@ import (
 // complicate this code with management of byte offsets and seeks at each read,
 // especially while this task of tokenizing byte streams is inherently single-
 // threaded.  Calling code is expected to manage it, typically via lexerState.
+//
+// Deprecated: the lexer no longer uses a Cursor, it scans bytes directly and
+// tracks line, column and byte offset as each rune is consumed (see input.go).
+// The Cursor is kept, unchanged, for code that used it directly.
 type Cursor interface {
 	// NextRune is called to extend the cursor by reading the next rune from
 	// input.  Also updates the pending string except when skipping spaces, and
@@ -82,6 +86,7 @@ type Cursor interface {
 	ErrorValue() error
 }
 
+// Deprecated: see Cursor.
 func NewCursor() Cursor {
 	// Token position (0, 0) is used for unknown, and position (1, 1) is for the
 	return cursorState{kTOKENPOS_ZERO, []rune{}, nil}
@@ -102,9 +107,6 @@ func (cursor cursorState) Pos() TokenPos {
 	return cursor.pos
 }
 
-// Arbitrary size for \t alignment.
-const CURSOR_TAB_STOP = 4
-
 // Returns the lines, columns offset for the runes in th pending buffer.
 func offset(runes []rune) (uint, uint) {
 	if len(runes) == 0 {
@@ -206,4 +208,4 @@ func (cursor cursorState) ResetPos() Cursor {
 	next := cursor
 	next.pos = kTOKENPOS_ZERO
 	return next
-}
\ No newline at end of file
+}
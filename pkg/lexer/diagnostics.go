@@ -0,0 +1,93 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/diagnostics.go
+
+package lexer
+
+import "fmt"
+
+// A Diagnostic describes malformed input at a position in the source.  These
+// do not stop the lexer, which produces an UnexpectedToken for the malformed
+// text and resumes scanning at the next plausible token, so that one pass over
+// a rulesheet reports all of its problems.  The diagnostics are collected by
+// the scanner (see TokenScanner.Diagnostics()) and are distinct from errors
+// reading the input, which are returned by Scan().
+type Diagnostic struct {
+	TokenPos
+	// The bytes of the offending token, see [files.go].  Zero if not scanned
+	// from a TokenScanner (e.g. when calling the token-specific readers).
+	Span     Span
+	Severity Severity
+	// One of the CODE_* constants, for tools that need to match on the problem
+	// without depending on the wording of its message.
+	Code    string
+	Message string
+}
+
+// Satisfies the error interface, including the position in the message.
+func (diag Diagnostic) Error() string {
+	return fmt.Sprintf("%d:%d: %s", diag.Line(), diag.Column(), diag.Message)
+}
+
+// How serious a diagnostic is.  The zero value is an error.
+type Severity uint8
+
+const (
+	SEVERITY_ERROR Severity = iota
+	SEVERITY_WARNING
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case SEVERITY_ERROR:
+		return "error"
+	case SEVERITY_WARNING:
+		return "warning"
+	}
+	return "UNKNOWN"
+}
+
+// The codes of the diagnostics produced by the lexer.
+const (
+	CODE_INVALID_UTF8         = "invalid-utf8"
+	CODE_UNEXPECTED_CHAR      = "unexpected-character"
+	CODE_UNKNOWN_OPERATOR     = "unknown-operator"
+	CODE_NON_ASCII_DIGIT      = "non-ascii-digit"
+	CODE_INTEGER_RANGE        = "integer-range"
+	CODE_UNTERMINATED_STRING  = "unterminated-string"
+	CODE_NEWLINE_IN_STRING    = "newline-in-string"
+	CODE_INVALID_ESCAPE       = "invalid-escape"
+	CODE_UNTERMINATED_COMMENT = "unterminated-comment"
+	CODE_DANGLING_SIGIL       = "dangling-sigil"
+	CODE_UNBALANCED_PAREN     = "unbalanced-paren"
+	CODE_UNCLOSED_PAREN       = "unclosed-paren"
+	CODE_MALFORMED_META       = "malformed-meta"
+)
+
+// Records an error diagnostic at the indicated position.  Its span is filled in
+// by Scan() with the span of the token being scanned when it was reported.
+func (reader *lexerState) errorAt(pos TokenPos, code, format string, args ...any) {
+	reader.diagnostics = append(reader.diagnostics, Diagnostic{
+		TokenPos: pos,
+		Severity: SEVERITY_ERROR,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Returns the diagnostics found by the scanner so far, in the order found.
+func (reader *lexerState) Diagnostics() []Diagnostic {
+	return reader.diagnostics
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/diagnostics_test.go
+
+package lexer
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		input   string
+		images  []string
+		want    []Diagnostic
+	}{
+		{"unexpected characters", KIF, "(role \x01\x02 x)",
+			[]string{"(", "role", "\x01\x02", "x", ")"},
+			[]Diagnostic{{NewTokenPos(1, 7), Span{7, 9}, SEVERITY_ERROR,
+				CODE_UNEXPECTED_CHAR, `unexpected character '\x01'`}}},
+		{"invalid utf-8", KIF, "(a \xff\xfe b)",
+			[]string{"(", "a", "\xff\xfe", "b", ")"},
+			[]Diagnostic{{NewTokenPos(1, 4), Span{4, 6}, SEVERITY_ERROR,
+				CODE_INVALID_UTF8, "invalid UTF-8"}}},
+		{"unknown operator", GEL, "a <x b",
+			[]string{"a", "<", "x", "b"},
+			[]Diagnostic{{NewTokenPos(1, 3), Span{3, 4}, SEVERITY_ERROR,
+				CODE_UNKNOWN_OPERATOR, "unknown operator '<'"}}},
+		{"unknown operator before paren", GEL, "(a <(b))",
+			[]string{"(", "a", "<", "(", "b", ")", ")"},
+			[]Diagnostic{{NewTokenPos(1, 4), Span{4, 5}, SEVERITY_ERROR,
+				CODE_UNKNOWN_OPERATOR, "unknown operator '<'"}}},
+		{"whole rulesheet", KIF, "(role \"x)\n(init ?)\n(next ^)",
+			[]string{"(", "role", "\"x)", "(", "init", "?", ")", "(", "next", "^", ")"},
+			[]Diagnostic{
+				{NewTokenPos(1, 7), Span{7, 10}, SEVERITY_ERROR,
+					CODE_NEWLINE_IN_STRING, "newline in string"},
+				{NewTokenPos(2, 7), Span{17, 18}, SEVERITY_ERROR,
+					CODE_DANGLING_SIGIL, "dangling '?' without a variable name"},
+				{NewTokenPos(3, 7), Span{26, 27}, SEVERITY_ERROR,
+					CODE_UNKNOWN_OPERATOR, "unknown operator '^'"},
+				{NewTokenPos(1, 1), Span{1, 2}, SEVERITY_ERROR,
+					CODE_UNCLOSED_PAREN, "unclosed '(' at end of input"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{Dialect: tt.dialect})
+			images := []string{}
+			for token, err := range scanner.All() {
+				if err != nil {
+					t.Fatalf("All() yielded error %v", err)
+				}
+				images = append(images, token.Image())
+			}
+			if !reflect.DeepEqual(images, tt.images) {
+				t.Errorf("All() images = %q, want %q", images, tt.images)
+			}
+			if !reflect.DeepEqual(scanner.Diagnostics(), tt.want) {
+				t.Errorf("Diagnostics()\n  got %v\n want %v", scanner.Diagnostics(), tt.want)
+			}
+		})
+	}
+}
+
+// Returns an error (once) for the indicated read, without consuming input.
+type flakyReader struct {
+	input  *strings.Reader
+	failAt int
+	reads  *int
+}
+
+var errFlaky = errors.New("temporarily unavailable")
+
+func (fr flakyReader) ReadRune() (rune, int, error) {
+	*fr.reads += 1
+	if *fr.reads == fr.failAt {
+		return 0, 0, errFlaky
+	}
+	return fr.input.ReadRune()
+}
+
+func TestScan_ResumesAfterReadError(t *testing.T) {
+	reads := 0
+	scanner := NewScanner(flakyReader{strings.NewReader("(role x)"), 6, &reads}, Options{})
+	images := []string{}
+	for {
+		token, err := scanner.Scan()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err != errFlaky {
+				t.Fatalf("Scan() error = %v, want %v", err, errFlaky)
+			}
+			images = append(images, "<error>")
+			continue
+		}
+		images = append(images, token.Image())
+	}
+	want := "( role <error> x )"
+	if got := strings.Join(images, " "); got != want {
+		t.Errorf("Scan() produced %q, want %q", got, want)
+	}
+	if len(scanner.Diagnostics()) != 0 {
+		t.Errorf("Diagnostics() = %v, want none", scanner.Diagnostics())
+	}
+}
+
+func TestDiagnostic_Error(t *testing.T) {
+	diag := Diagnostic{TokenPos: NewTokenPos(3, 14), Code: CODE_INVALID_UTF8,
+		Message: "invalid UTF-8"}
+	if got, want := diag.Error(), "3:14: invalid UTF-8"; got != want {
+		t.Errorf("Diagnostic.Error() = %q, want %q", got, want)
+	}
+	if got, want := diag.Severity.String(), "error"; got != want {
+		t.Errorf("Severity.String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/dialect.go
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The source formats that the lexer can tokenize.  Each dialect has its own
+// comment syntax, keywords and set of operators (see the tables below).
+type Dialect int
+
+const (
+	// GDL in Knowledge Interchange Format, e.g. `(<= (next ?x) (does ?x ?y))`.
+	// This is the default, as it's the format used by most published games.
+	KIF Dialect = iota
+	// GDL in Human Readable Format, e.g. `next(X) :- does(X, Y)`.
+	HRF
+	// Goal Expression Language, the format of .ggd files.
+	GEL
+)
+
+// Returns the conventional name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case KIF:
+		return "KIF"
+	case HRF:
+		return "HRF"
+	case GEL:
+		return "GEL"
+	}
+	return "UNKNOWN"
+}
+
+// Returns the dialect with the (case-insensitive) name, such as "gel".
+func ParseDialect(name string) (Dialect, error) {
+	for _, dialect := range []Dialect{KIF, HRF, GEL} {
+		if strings.EqualFold(name, dialect.String()) {
+			return dialect, nil
+		}
+	}
+	return KIF, fmt.Errorf("unknown dialect %q", name)
+}
+
+// Options for configuring a TokenScanner.  The zero value scans KIF.
+type Options struct {
+	Dialect Dialect
+
+	// The file being scanned, from a FileSet, which receives the line table as
+	// the input is read.  Token spans are then positions within the FileSet.
+	// If nil, spans are relative to an implicit file at base 1 (offset + 1).
+	File *File
+
+	// Scans losslessly, attaching the spaces, newlines and comments between the
+	// tokens to them as Trivia instead of skipping spaces and producing COMMENT
+	// tokens.  The trivia at the end of input is attached to the EOF token, which
+	// All() then yields as its final token, so that concatenating the FullText()
+	// of the tokens reproduces the input.  See [trivia.go].
+	Trivia bool
+
+	// The table to intern the names of identifiers and variables in, so that the
+	// tokens' Symbol() can be compared (and the table shared) by what is built
+	// from them.  Only the first occurrence of a name in a scan locks the table.
+	// If nil, no names are interned and Symbol() is NoSymbol.  See [intern.go].
+	Symbols *Symbols
+
+	// The unit that the columns of TokenPos values are counted in, and for
+	// COLUMNS_RUNES, the distance between tab stops (CURSOR_TAB_STOP if zero).
+	// See [columns.go] for converting columns between encodings.
+	Columns  ColumnEncoding
+	TabWidth int
+}
+
+// The lexical properties that differ between dialects.
+type dialect struct {
+	// Keywords are held as a TokenType so that tokens share the same (boxed)
+	// instance, as the operators do, rather than converting on each use.
+	keywords  map[string]TokenType
+	operators map[string]TokenType
+	// Proper prefixes of the operator images, used to determine when to stop
+	// reading runes for a (multi-rune) operator.
+	prefixes map[string]bool
+
+	// Runes that, when immediately followed by a name, make it a variable.
+	sigils string
+	// When true, names that begin with an uppercase letter are variables.
+	capitalVariables bool
+
+	// The line comment marker for doc comments and the keywords of declarations
+	// they may document, if the dialect has doc comments.  See [comments.go].
+	docMarker    string
+	declarations map[string]bool
+}
+
+// Stands in for a dialect's line comment marker within its operator table,
+// so that markers like `%%` are matched the same way as operators are.
+type commentMarker struct{ image string }
+
+func (marker commentMarker) TypeString() string { return "COMMENT" }
+func (marker commentMarker) Image() string      { return marker.image }
+
+// Like commentMarker, for the opening marker of a block comment.  Block comments
+// do not nest, they extend from the opening marker to the first closing marker.
+type blockCommentMarker struct{ open, close string }
+
+func (marker blockCommentMarker) TypeString() string { return "COMMENT" }
+func (marker blockCommentMarker) Image() string      { return marker.open }
+
+// Block comments, as used in the grammars, for the dialects that allow them.
+var BLOCK_COMMENT = blockCommentMarker{"(*", "*)"}
+
+func newDialect() *dialect {
+	return &dialect{
+		keywords:     make(map[string]TokenType),
+		operators:    make(map[string]TokenType),
+		prefixes:     make(map[string]bool),
+		declarations: make(map[string]bool),
+	}
+}
+
+// Looks up the dialect for the indicated option, defaulting to KIF.
+func (opts Options) dialect() *dialect {
+	if spec, ok := dialects[opts.Dialect]; ok {
+		return spec
+	}
+	return dialects[KIF]
+}
+
+func (d *dialect) defineKeywords(images ...string) {
+	for _, image := range images {
+		d.keywords[image] = KeywordToken{image}
+	}
+}
+
+func (d *dialect) defineDeclarations(images ...string) {
+	for _, image := range images {
+		d.declarations[image] = true
+	}
+}
+
+func (d *dialect) defineOperators(optypes ...TokenType) {
+	for _, optype := range optypes {
+		image := []rune(optype.Image())
+		d.operators[string(image)] = optype
+		for i := 1; i < len(image); i++ {
+			d.prefixes[string(image[:i])] = true
+		}
+	}
+}
+
+var dialects = map[Dialect]*dialect{
+	KIF: newDialect(),
+	HRF: newDialect(),
+	GEL: newDialect(),
+}
+
+func init() {
+	kif := dialects[KIF]
+	// Special relations defined for the semantics of GDL.
+	kif.defineKeywords("role", "legal", "next", "does", "goal", "terminal")
+	// Additional special relations for GDL-II.
+	kif.defineKeywords("sees", "random")
+	// These could be inferred or defined in terms of other rules.
+	kif.defineKeywords("init", "input", "base")
+	// Some boolean relations that GDL assumes existence of.
+	kif.defineKeywords("true", "or", "and", "not", "distinct")
+
+	kif.defineOperators(&EXPR_START, &EXPR_END, &ARROW_LD, commentMarker{";"})
+	kif.sigils = "?"
+
+	// HRF has the same relations as KIF, with operators for `<=`, `and`, `not`
+	// and `distinct` (`:-`, `&`, `~` and `#`), `%` for line comments and `(* *)`
+	// for block comments.
+	hrf := dialects[HRF]
+	hrf.defineKeywords("role", "legal", "next", "does", "goal", "terminal",
+		"sees", "random", "init", "input", "base", "true", "distinct")
+	hrf.defineOperators(&EXPR_START, &EXPR_END, COMMA, COLON_DASH,
+		AMPERSAND, TILDE, HASH, COLON_COLON, ARROW_RD, commentMarker{"%"},
+		BLOCK_COMMENT)
+	hrf.capitalVariables = true
+
+	// GEL extends GDL with definitions for data, surfaces and imported rules,
+	// and a much larger set of operators, with `%%` for line comments and `(* *)`
+	// for block comments.  A block of `%%` comments immediately preceding a
+	// declaration is its doc comment.  Variables are named as in KIF, or with `\`
+	// when they are bound in a comprehension.
+	gel := dialects[GEL]
+	gel.defineKeywords("role", "legal", "next", "does", "goal", "terminal",
+		"sees", "random", "init", "true", "or", "and", "not")
+	gel.defineKeywords("consult", "from", "data", "surface", "where", "let", "in",
+		"persist")
+	gel.defineOperators(&EXPR_START, &EXPR_END, &ARROW_LD,
+		COLON_DASH, ARROW_RD, ARROW_R, ARROW_L, ARROW_LRD,
+		DOLLAR_EQ, COLON_EQ, EQUALS, COLON,
+		LT_LT, GT_GT, LT_LT_LT, TRIPLE_EQ, TRIPLE_NE, LT_HASH_GT,
+		AT_SIGN, DOT_DOT, DOT, COMMA, PIPE, BACKSLASH,
+		OPEN_BRACKET, CLOSE_BRACKET, OPEN_BRACE, CLOSE_BRACE,
+		OPEN_DBRACE, CLOSE_DBRACE, OPEN_BRACE_BRACKET, CLOSE_BRACKET_BRACE,
+		commentMarker{"%%"}, BLOCK_COMMENT)
+	gel.sigils = "?\\"
+	gel.docMarker = "%%"
+	gel.defineDeclarations("role", "data", "surface", "init", "consult")
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/dialect_test.go
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScan_Dialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		input   string
+		want    string
+	}{
+		{"KIF rule", KIF, "(<= (next ?x) (does ?x ?y)) ; effect",
+			"( <= ( KEYWORD ?x ) ( KEYWORD ?x ?y ) ) COMMENT"},
+		{"KIF distinct", KIF, "(distinct ?a ?b)",
+			"( KEYWORD ?a ?b )"},
+		{"KIF has no GEL operators", KIF, "x := y",
+			"x UNEXPECTED UNEXPECTED y"},
+		{"KIF percent is not a comment", KIF, "% note",
+			"UNEXPECTED note"},
+		{"HRF rule", HRF, "next(X) :- does(X, Y) & ~true(Y) % effect",
+			"KEYWORD ( X ) :- KEYWORD ( X , Y ) & ~ KEYWORD ( Y ) COMMENT"},
+		{"HRF distinct", HRF, "distinct(A, B) # C",
+			"KEYWORD ( A , B ) # C"},
+		{"HRF next shorthand", HRF, "p :: q ==> r",
+			"p :: q ==> r"},
+		{"HRF semicolon is not a comment", HRF, "; note",
+			"UNEXPECTED note"},
+		{"GEL rule", GEL, "terminal :- not board.Open %% no moves",
+			"KEYWORD :- KEYWORD board . Open COMMENT"},
+		{"GEL declarations", GEL, "data Hand := ROCK << PAPER",
+			"KEYWORD Hand := ROCK << PAPER"},
+		{"GEL surface", GEL, "surface B <=> {[ x | \\row <- 1..3 ]} where (",
+			"KEYWORD B <=> {[ x | \\row <- 1 .. 3 ]} KEYWORD ("},
+		{"GEL single percent", GEL, "% note",
+			"UNEXPECTED note"},
+		{"GEL input is not a keyword", GEL, "input",
+			"input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(tt.input), Options{Dialect: tt.dialect})
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			got := make([]string, len(tokens))
+			for i, token := range tokens {
+				switch token.TypeString() {
+				case "KEYWORD", "UNEXPECTED", "COMMENT":
+					got[i] = token.TypeString()
+				default:
+					got[i] = token.Image()
+				}
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("ScanAll(%s)\n  got %s\n want %s",
+					tt.dialect, strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_String(t *testing.T) {
+	for dialect, want := range map[Dialect]string{
+		KIF: "KIF", HRF: "HRF", GEL: "GEL", Dialect(7): "UNKNOWN",
+	} {
+		if got := dialect.String(); got != want {
+			t.Errorf("Dialect(%d).String() = %s, want %s", int(dialect), got, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/files.go
+
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Pos is a compact byte position within a FileSet, similar to go/token's Pos.
+// Each file in the set is given a range of Pos values [base, base+size], so a
+// Pos identifies both the file and the byte offset within it.  Unlike TokenPos
+// it does not saturate, and can be converted to a full Position via its File.
+//
+// The zero value is NoPos, which is not a position in any file.
+type Pos int
+
+const NoPos Pos = 0
+
+func (p Pos) IsValid() bool { return p != NoPos }
+
+// The range of bytes [Start, End) that a token (or diagnostic) spans.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Returns the number of bytes in the span.
+func (span Span) Len() int { return int(span.End - span.Start) }
+
+// A fully resolved source position, with 1-indexed line and (byte) column.
+type Position struct {
+	Filename string
+	Offset   int // byte offset, starting at 0
+	Line     int // line number, starting at 1
+	Column   int // column number in bytes, starting at 1
+}
+
+func (pos Position) IsValid() bool { return pos.Line > 0 }
+
+// Formats the position as `file:line:column`, omitting what is unknown.
+func (pos Position) String() string {
+	text := pos.Filename
+	if pos.IsValid() {
+		if text != "" {
+			text += ":"
+		}
+		text += fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	if text == "" {
+		text = "-"
+	}
+	return text
+}
+
+// A File is a source within a FileSet, with its name, size and line table.
+// The line table is populated by the scanner as it reads the file, or can be
+// filled in directly with AddLine or SetLinesForContent.
+type File struct {
+	name string
+	base int
+	size int
+
+	mutex sync.Mutex
+	// Byte offsets of the first character of each line, lines[0] is always 0.
+	lines []int
+}
+
+func (file *File) Name() string { return file.name }
+func (file *File) Base() int    { return file.base }
+func (file *File) Size() int    { return file.size }
+
+// Returns the number of lines known to the line table.
+func (file *File) LineCount() int {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	return len(file.lines)
+}
+
+// Adds the offset of a new line's start to the line table.  Offsets that are
+// not after the previous line's start, or that are beyond the file, are ignored.
+func (file *File) AddLine(offset int) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if offset > file.lines[len(file.lines)-1] && offset <= file.size {
+		file.lines = append(file.lines, offset)
+	}
+}
+
+// Replaces the line table with the line starts found in the content.  As when
+// scanning, "\n", "\r\n" and a lone "\r" are each a line break.
+func (file *File) SetLinesForContent(content []byte) {
+	lines := []int{0}
+	for offset, b := range content {
+		crlf := b == '\r' && offset+1 < len(content) && content[offset+1] == '\n'
+		if (b == '\n' || b == '\r' && !crlf) && offset+1 <= file.size {
+			lines = append(lines, offset+1)
+		}
+	}
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	file.lines = lines
+}
+
+// Returns the Pos for a byte offset in this file, clamped to the file's size.
+func (file *File) Pos(offset int) Pos {
+	offset = max(0, min(offset, file.size))
+	return Pos(file.base + offset)
+}
+
+// Returns the byte offset within this file for a Pos in its range.
+func (file *File) Offset(p Pos) int {
+	return max(0, min(int(p)-file.base, file.size))
+}
+
+// Returns the 1-indexed line number that contains the position.
+func (file *File) Line(p Pos) int {
+	return file.Position(p).Line
+}
+
+// Returns the Pos of the first character of the (1-indexed) line.
+func (file *File) LineStart(line int) Pos {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if line < 1 || line > len(file.lines) {
+		return NoPos
+	}
+	return Pos(file.base + file.lines[line-1])
+}
+
+// Resolves a Pos in this file into its line and (byte) column.
+func (file *File) Position(p Pos) Position {
+	if !p.IsValid() {
+		return Position{}
+	}
+	offset := file.Offset(p)
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	line := sort.Search(len(file.lines), func(i int) bool {
+		return file.lines[i] > offset
+	})
+	return Position{file.name, offset, line, offset - file.lines[line-1] + 1}
+}
+
+// A FileSet is the collection of source files read together, such as the rules
+// of a game and the files it consults.  Positions are unique across the set.
+type FileSet struct {
+	mutex sync.RWMutex
+	base  int
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	// The base starts at 1 so that NoPos is not a position in any file.
+	return &FileSet{base: 1}
+}
+
+// Adds a file to the set with the given name and size (in bytes).  The size
+// must be known so that later files have distinct positions; positions beyond
+// the size of the file are clamped to its end.
+func (set *FileSet) AddFile(name string, size int) *File {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	size = max(0, size)
+	file := &File{name: name, base: set.base, size: size, lines: []int{0}}
+	// One extra position for the end of file, so each file has a distinct EOF.
+	set.base += size + 1
+	set.files = append(set.files, file)
+	return file
+}
+
+// Returns the file that contains the position, or nil if none does.
+func (set *FileSet) File(p Pos) *File {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	i := sort.Search(len(set.files), func(i int) bool {
+		return set.files[i].base > int(p)
+	})
+	if i == 0 || !p.IsValid() {
+		return nil
+	}
+	file := set.files[i-1]
+	if int(p) > file.base+file.size {
+		return nil
+	}
+	return file
+}
+
+// Resolves a Pos into its file name, line and column.
+func (set *FileSet) Position(p Pos) Position {
+	if file := set.File(p); file != nil {
+		return file.Position(p)
+	}
+	return Position{}
+}
+
+// Returns the files in the set, in the order they were added.
+func (set *FileSet) Files() []*File {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return append([]*File{}, set.files...)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/files_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileSet_Position(t *testing.T) {
+	fset := NewFileSet()
+	rules := fset.AddFile("rules.gel", 13)
+	rules.SetLinesForContent([]byte("role x\nrole o"))
+	data := fset.AddFile("data.gel", 5)
+
+	tests := []struct {
+		name string
+		pos  Pos
+		want Position
+	}{
+		{"no pos", NoPos, Position{}},
+		{"first byte", rules.Pos(0), Position{"rules.gel", 0, 1, 1}},
+		{"end of line", rules.Pos(6), Position{"rules.gel", 6, 1, 7}},
+		{"second line", rules.Pos(9), Position{"rules.gel", 9, 2, 3}},
+		{"end of file", rules.Pos(13), Position{"rules.gel", 13, 2, 7}},
+		{"next file", data.Pos(0), Position{"data.gel", 0, 1, 1}},
+		{"clamped", data.Pos(99), Position{"data.gel", 5, 1, 6}},
+		{"beyond set", Pos(data.Base() + 6), Position{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fset.Position(tt.pos); got != tt.want {
+				t.Errorf("FileSet.Position(%d) = %v, want %v", tt.pos, got, tt.want)
+			}
+		})
+	}
+	if got := fset.Files(); !reflect.DeepEqual(got, []*File{rules, data}) {
+		t.Errorf("FileSet.Files() = %v, want [rules data]", got)
+	}
+	if got, want := rules.LineStart(2), rules.Pos(7); got != want {
+		t.Errorf("File.LineStart(2) = %d, want %d", got, want)
+	}
+}
+
+func TestFile_LineBreaks(t *testing.T) {
+	content := "(role x)\r\n(role o)\r(init)\n\r\n"
+	fset := NewFileSet()
+	set := fset.AddFile("set.kif", len(content))
+	set.SetLinesForContent([]byte(content))
+	scanned := fset.AddFile("scanned.kif", len(content))
+	if _, err := ScanAll(strings.NewReader(content), Options{File: scanned}); err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	want := []int{0, 10, 19, 26, 28}
+	for _, file := range []*File{set, scanned} {
+		var starts []int
+		for line := 1; line <= file.LineCount(); line++ {
+			starts = append(starts, file.Offset(file.LineStart(line)))
+		}
+		if !reflect.DeepEqual(starts, want) {
+			t.Errorf("%s line starts = %v, want %v", file.Name(), starts, want)
+		}
+	}
+}
+
+func TestPosition_String(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{Position{}, "-"},
+		{Position{Filename: "rules.kif"}, "rules.kif"},
+		{Position{"", 1500, 1, 1501}, "1:1501"},
+		{Position{"rules.kif", 10, 2, 3}, "rules.kif:2:3"},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("Position.String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestScan_Spans(t *testing.T) {
+	input := "; gøød\n(role \"xø\")\n\t?p " + strings.Repeat("a", 1100) + " b"
+	fset := NewFileSet()
+	fset.AddFile("other.kif", 40)
+	file := fset.AddFile("rules.kif", len(input))
+
+	tokens, err := ScanAll(strings.NewReader(input), Options{File: file})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	want := []struct {
+		image      string
+		start, end Position
+	}{
+		{"; gøød", Position{"rules.kif", 0, 1, 1}, Position{"rules.kif", 8, 1, 9}},
+		{"(", Position{"rules.kif", 9, 2, 1}, Position{"rules.kif", 10, 2, 2}},
+		{"role", Position{"rules.kif", 10, 2, 2}, Position{"rules.kif", 14, 2, 6}},
+		{"\"xø\"", Position{"rules.kif", 15, 2, 7}, Position{"rules.kif", 20, 2, 12}},
+		{")", Position{"rules.kif", 20, 2, 12}, Position{"rules.kif", 21, 2, 13}},
+		{"?p", Position{"rules.kif", 23, 3, 2}, Position{"rules.kif", 25, 3, 4}},
+		{strings.Repeat("a", 1100),
+			Position{"rules.kif", 26, 3, 5}, Position{"rules.kif", 1126, 3, 1105}},
+		{"b", Position{"rules.kif", 1127, 3, 1106}, Position{"rules.kif", 1128, 3, 1107}},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("ScanAll() produced %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, token := range tokens {
+		if token.Image() != want[i].image {
+			t.Errorf("token %d image = %q, want %q", i, token.Image(), want[i].image)
+		}
+		start, end := fset.Position(token.Span.Start), fset.Position(token.Span.End)
+		if start != want[i].start || end != want[i].end {
+			t.Errorf("token %q span = %v-%v, want %v-%v",
+				token.Image(), start, end, want[i].start, want[i].end)
+		}
+	}
+	if file.LineCount() != 3 {
+		t.Errorf("File.LineCount() = %d, want 3", file.LineCount())
+	}
+}
+
+func TestScan_SpansWithoutFile(t *testing.T) {
+	tokens, err := ScanAll(strings.NewReader("  (next ?x)"), Options{})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	want := []Span{{3, 4}, {4, 8}, {9, 11}, {11, 12}}
+	got := []Span{}
+	for _, token := range tokens {
+		got = append(got, token.Span)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanAll() spans = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/input.go
+
+package lexer
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// The lexer reads its input as bytes, scanning tokens directly from a buffer.
+// When scanning an in-memory source (see NewBytesScanner) the buffer is that
+// source and nothing is copied, otherwise the buffer holds a window of the input
+// from the beginning of the current token, and is refilled as tokens are read.
+//
+// The line and column of the read position are updated incrementally as each
+// rune is consumed, and the byte offset is always known, so there is no need to
+// rescan a token's image to compute the position that follows it.  The image of
+// a token is only copied out of the buffer when it is not already interned
+// (for identifiers, variables and integers) or shared (keywords and operators).
+type input struct {
+	buf []byte
+	// Index in buf of the next byte to be read.
+	pos int
+	// Index in buf of the start of the current token (or trivia), which must be
+	// kept in the buffer when it is refilled.
+	mark int
+	// Byte offset in the source of buf[0].
+	base int
+
+	// The source of additional bytes, nil when scanning an in-memory source.
+	reader io.Reader
+	// The error from the most recent read of input.  Reading stops at an error;
+	// io.EOF persists, other errors are cleared by the scanner once returned.
+	err error
+
+	// The position of buf[pos], and the File (if any) to add line starts to.
+	// Columns are counted as configured by the Options, see [columns.go].
+	line, col uint
+	file      *File
+	columns   columnCounter
+
+	// The ranges of invalid UTF-8 consumed since they were last taken, see
+	// takeInvalid().  Adjacent invalid bytes are a single range.
+	invalid []invalidRange
+}
+
+// A range of bytes [start, end) that is not valid UTF-8, and its position.
+type invalidRange struct {
+	start, end int
+	pos        TokenPos
+}
+
+// The byte order mark, which some editors write at the start of UTF-8 files.
+const UTF8_BOM = "\uFEFF"
+
+// The size of the buffer for input of unknown length, and the minimum free
+// space to read into before the buffer is compacted.
+const minRead = 4096
+
+func newInput(reader io.Reader, opts Options) input {
+	return input{reader: reader, line: 1, col: 1, file: opts.File,
+		columns: newColumnCounter(opts.Columns, opts.TabWidth)}
+}
+
+func newBytesInput(data []byte, opts Options) input {
+	in := newInput(nil, opts)
+	in.buf, in.err = data, io.EOF
+	return in
+}
+
+// Ensures that at least n bytes are available after the read position, reading
+// from input as needed.  Returns false if fewer are available, at end of input
+// or if there was an error reading it.
+//
+// The buffer is first sized to the input when its length is known (as for a
+// strings.Reader holding a message), so a short input is read into a buffer of
+// its own size.  Bytes before the current token are discarded to make room, and
+// the buffer only grows when the current token fills it.
+func (in *input) fill(n int) bool {
+	for len(in.buf)-in.pos < n {
+		if in.reader == nil || in.err != nil {
+			return false
+		}
+		if in.buf == nil {
+			size := minRead
+			if sized, ok := in.reader.(interface{ Len() int }); ok && sized.Len() < minRead {
+				// One more byte than remains, so that the read which finds the end of
+				// the input has room without growing the buffer.
+				size = sized.Len() + 1
+			}
+			in.buf = make([]byte, 0, size)
+		}
+		if cap(in.buf)-len(in.buf) < minRead && in.mark > 0 {
+			// Discard what was read before the current token.
+			kept := copy(in.buf, in.buf[in.mark:])
+			in.buf = in.buf[:kept]
+			in.pos -= in.mark
+			in.base += in.mark
+			in.mark = 0
+		}
+		if free := cap(in.buf) - len(in.buf); free == 0 || free < len(in.buf) && free < minRead {
+			grown := make([]byte, len(in.buf), max(2*cap(in.buf), minRead))
+			copy(grown, in.buf)
+			in.buf = grown
+		}
+		count, err := in.reader.Read(in.buf[len(in.buf):cap(in.buf)])
+		in.buf = in.buf[:len(in.buf)+count]
+		in.err = err
+	}
+	return true
+}
+
+// Returns the rune at the read position and its size in bytes, without reading
+// past it.  The size is zero at the end of the (available) input.  An invalid
+// byte is returned as utf8.RuneError with a size of 1.
+func (in *input) peek() (rune, int) {
+	return in.peekAt(0)
+}
+
+// Returns the rune that is `offset` bytes after the read position.
+func (in *input) peekAt(offset int) (rune, int) {
+	if !in.fill(offset + 1) {
+		return 0, 0
+	}
+	if b := in.buf[in.pos+offset]; b < utf8.RuneSelf {
+		return rune(b), 1
+	}
+	in.fill(offset + utf8.UTFMax)
+	return utf8.DecodeRune(in.buf[in.pos+offset:])
+}
+
+// Consumes the next rune, returning it, or zero at the end of input.
+func (in *input) next() rune {
+	r, size := in.peek()
+	in.advance(r, size)
+	return r
+}
+
+// Consumes `size` bytes of the rune `r`, updating the line and column.  A line
+// break may be "\n", "\r\n" or a lone "\r", each is a single line break.
+func (in *input) advance(r rune, size int) {
+	switch {
+	case size == 0:
+	case ' ' <= r && r < 0x7F:
+		in.col += 1
+	case r == '\n':
+		in.pos += size
+		in.newLine()
+		return
+	case r == '\r':
+		in.pos += size
+		// The '\n' of a "\r\n" pair is the line break, not the '\r'.
+		if next, _ := in.peek(); next != '\n' {
+			in.newLine()
+		}
+		return
+	case r == utf8.RuneError && size == 1:
+		offset := in.offset()
+		if n := len(in.invalid); n > 0 && in.invalid[n-1].end == offset {
+			in.invalid[n-1].end += 1
+		} else {
+			in.invalid = append(in.invalid, invalidRange{offset, offset + 1, in.tokenPos()})
+		}
+		fallthrough
+	default:
+		in.col = in.columns.after(in.col, r, size)
+	}
+	in.pos += size
+}
+
+// Moves to the start of the next line, after a line break.
+func (in *input) newLine() {
+	in.line, in.col = in.line+1, 1
+	if in.file != nil {
+		in.file.AddLine(in.offset())
+	}
+}
+
+// Returns true for the runes that begin a line break, '\n' and '\r'.
+func isLineBreak(r rune) bool {
+	return r == '\n' || r == '\r'
+}
+
+// Returns the offset of the start of the last line of the text.
+func lastLineStart(text []byte) int {
+	return bytes.LastIndexAny(text, "\r\n") + 1
+}
+
+// Returns the number of line breaks in the text.
+func countLineBreaks(text []byte) int {
+	return bytes.Count(text, []byte{'\n'}) + bytes.Count(text, []byte{'\r'}) -
+		bytes.Count(text, []byte("\r\n"))
+}
+
+// Returns (and forgets) the ranges of invalid UTF-8 consumed so far.
+func (in *input) takeInvalid() []invalidRange {
+	invalid := in.invalid
+	in.invalid = in.invalid[:0]
+	return invalid
+}
+
+// Consumes a byte order mark at the very start of the input, without counting
+// a column for it.  Returns true if there was one.
+func (in *input) skipBOM() bool {
+	if in.offset() != 0 || !in.lookingAt(UTF8_BOM) {
+		return false
+	}
+	in.pos += len(UTF8_BOM)
+	return true
+}
+
+// Marks the read position as the start of a token (or of some trivia).
+func (in *input) begin() TokenPos {
+	in.mark = in.pos
+	return in.tokenPos()
+}
+
+// Returns the bytes from the mark to the read position.  These are only valid
+// until more input is read, copy them (e.g., with string()) to keep them.
+func (in *input) image() []byte {
+	return in.buf[in.mark:in.pos]
+}
+
+// The TokenPos of the read position, with the flag unknown.
+func (in *input) tokenPos() TokenPos {
+	return rawTokenPos(in.line, in.col, kTOKENPOS_FLAG_UNK)
+}
+
+// The byte offset in the source of the read position.
+func (in *input) offset() int {
+	return in.base + in.pos
+}
+
+// The byte offset in the source of the mark.
+func (in *input) markOffset() int {
+	return in.base + in.mark
+}
+
+// Consumes the next n bytes, which must end on a rune boundary.
+func (in *input) consume(n int) {
+	for end := in.pos + n; in.pos < end; {
+		r, size := in.peek()
+		in.advance(r, size)
+	}
+}
+
+// Returns true if the input at the read position begins with the text.
+func (in *input) lookingAt(text string) bool {
+	return in.fill(len(text)) && string(in.buf[in.pos:in.pos+len(text)]) == text
+}
+
+// Consumes runes while the predicate is true.
+func (in *input) skipWhile(predicate func(rune) bool) {
+	for {
+		r, size := in.peek()
+		if size == 0 || !predicate(r) {
+			return
+		}
+		in.advance(r, size)
+	}
+}
+
+// Returns true if the rune at the read position is an invalid UTF-8 byte.
+func (in *input) atInvalid() bool {
+	r, size := in.peek()
+	return r == utf8.RuneError && size == 1
+}
+
+// Adapts an io.RuneReader that is not also an io.Reader to read bytes from.
+type runeInput struct{ runes io.RuneReader }
+
+// A rune is read at a time, so that reading does not block waiting for more of
+// an interactive input than is needed for the next token.
+func (in runeInput) Read(buf []byte) (int, error) {
+	r, _, err := in.runes.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	return utf8.EncodeRune(buf, r), nil
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/input_test.go
+
+package lexer
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Creates a lexer for reading from the text, as though the text begins at the
+// line and column of pos, for testing the individual token readers.
+func newTestLexer(text string, pos TokenPos, dialect Dialect) *lexerState {
+	opts := Options{Dialect: dialect}
+	reader := newLexer(newInput(strings.NewReader(text), opts), opts)
+	reader.line, reader.col = pos.Line(), pos.Column()
+	return reader
+}
+
+// Returns the input that has not yet been consumed, without consuming it.
+func (in *input) remaining() string {
+	for in.fill(len(in.buf) - in.pos + 1) {
+	}
+	return string(in.buf[in.pos:])
+}
+
+func TestInput_Positions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLine uint
+		wantCol  uint
+	}{
+		{"empty", "", 1, 1},
+		{"ascii", "abc", 1, 4},
+		{"multibyte", "gøød", 1, 5},
+		{"newline", "ab\ncd", 2, 3},
+		{"tab", "\tx", 1, 6},
+		{"tab after text", "ab\tx", 1, 6},
+		{"control characters", "a\x01b", 1, 4},
+		{"crlf", "ab\r\ncd", 2, 3},
+		{"lone carriage return", "a\rb", 2, 2},
+		{"blank crlf lines", "\r\n\r\n\rx", 4, 2},
+		{"invalid utf-8", "a\xffb", 1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := newInput(strings.NewReader(tt.input), Options{})
+			for in.next() != 0 {
+			}
+			if in.line != tt.wantLine || in.col != tt.wantCol {
+				t.Errorf("input position = %d:%d, want %d:%d",
+					in.line, in.col, tt.wantLine, tt.wantCol)
+			}
+			if in.offset() != len(tt.input) {
+				t.Errorf("input.offset() = %d, want %d", in.offset(), len(tt.input))
+			}
+		})
+	}
+}
+
+func TestInput_Refill(t *testing.T) {
+	// Reading a byte at a time splits the multi-byte runes across reads, and a
+	// long input has its buffer compacted from the mark as tokens are read.
+	text := strings.Repeat("(cell ø 12) ", 1000)
+	in := newInput(iotest.OneByteReader(strings.NewReader(text)), Options{})
+	var images []string
+	for {
+		in.skipWhile(func(r rune) bool { return r == ' ' })
+		in.begin()
+		in.skipWhile(func(r rune) bool { return r != ' ' })
+		if len(in.image()) == 0 {
+			break
+		}
+		images = append(images, string(in.image()))
+	}
+	if got := strings.Join(images, " ") + " "; got != text {
+		t.Errorf("images = %.40q..., want %.40q...", got, text)
+	}
+	if in.err != io.EOF {
+		t.Errorf("input.err = %v, want io.EOF", in.err)
+	}
+	if len(in.buf) >= len(text) {
+		t.Errorf("input buffer holds %d bytes, expected it to be compacted", len(in.buf))
+	}
+}
+
+func TestInput_BufferSize(t *testing.T) {
+	// A short input of known length is read into a buffer of its own size.
+	message := "(PLAY match_1 ((mark 2 3) noop))"
+	in := newInput(strings.NewReader(message), Options{})
+	for in.next() != 0 {
+		in.begin()
+	}
+	if cap(in.buf) != len(message)+1 {
+		t.Errorf("input buffer has capacity %d, want %d", cap(in.buf), len(message)+1)
+	}
+
+	// A token longer than the buffer grows it, however the input is read.
+	long := strings.Repeat("x", 3*minRead)
+	in = newInput(iotest.OneByteReader(strings.NewReader(long+" y")), Options{})
+	in.begin()
+	in.skipWhile(func(r rune) bool { return r != ' ' })
+	if image := string(in.image()); image != long {
+		t.Errorf("image has %d bytes, want %d", len(image), len(long))
+	}
+}
+
+func TestNewBytesScanner(t *testing.T) {
+	text := "(does ?player (mark 1 2))\n"
+	want, err := ScanAll(strings.NewReader(text), Options{})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	scanner := NewBytesScanner([]byte(text), Options{})
+	for i := 0; ; i++ {
+		token, err := scanner.Scan()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("NewBytesScanner() scanned %d tokens, want %d", i, len(want))
+			}
+			break
+		}
+		if i >= len(want) || !reflect.DeepEqual(token, want[i]) {
+			t.Fatalf("token %d = %v", i, token)
+		}
+	}
+}
+
+func TestScan_InputNormalisation(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []string
+		wantPos   []TokenPos
+		wantDiags []Diagnostic
+	}{
+		{"byte order mark", "\uFEFF(role x)",
+			[]string{"(", "role", "x", ")"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(1, 2), NewTokenPos(1, 7), NewTokenPos(1, 8)},
+			nil},
+		{"crlf", "(role x)\r\n; note\r\n(role o)",
+			[]string{"(", "role", "x", ")", "; note", "(", "role", "o", ")"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(1, 2), NewTokenPos(1, 7), NewTokenPos(1, 8),
+				NewTokenPos(2, 1), NewTokenPos(3, 1), NewTokenPos(3, 2), NewTokenPos(3, 7), NewTokenPos(3, 8)},
+			nil},
+		{"lone cr", "x\ry\r\rz",
+			[]string{"x", "y", "z"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(2, 1), NewTokenPos(4, 1)},
+			nil},
+		{"invalid in identifier", "(ab\xffcd)",
+			[]string{"(", "ab", "\xff", "cd", ")"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(1, 2), NewTokenPos(1, 4), NewTokenPos(1, 5), NewTokenPos(1, 7)},
+			[]Diagnostic{{NewTokenPos(1, 4), Span{4, 5}, SEVERITY_ERROR, CODE_INVALID_UTF8, "invalid UTF-8"}}},
+		{"invalid in string", "\"g\xc3\xb8\xc3d\"",
+			[]string{"\"g\xc3\xb8\xc3d\""},
+			[]TokenPos{NewTokenPos(1, 1)},
+			[]Diagnostic{{NewTokenPos(1, 4), Span{5, 6}, SEVERITY_ERROR, CODE_INVALID_UTF8, "invalid UTF-8"}}},
+		{"invalid in comment", "x ; \xe2\x82 and \xff\xfe\r\ny",
+			[]string{"x", "; \xe2\x82 and \xff\xfe", "y"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(1, 3), NewTokenPos(2, 1)},
+			[]Diagnostic{
+				{NewTokenPos(1, 5), Span{5, 7}, SEVERITY_ERROR, CODE_INVALID_UTF8, "invalid UTF-8"},
+				{NewTokenPos(1, 12), Span{12, 14}, SEVERITY_ERROR, CODE_INVALID_UTF8, "invalid UTF-8"}}},
+		{"encoded replacement character", "x \uFFFD",
+			[]string{"x", "\uFFFD"},
+			[]TokenPos{NewTokenPos(1, 1), NewTokenPos(1, 3)},
+			[]Diagnostic{{NewTokenPos(1, 3), Span{3, 6}, SEVERITY_ERROR, CODE_UNKNOWN_OPERATOR,
+				"unknown operator '\uFFFD'"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{})
+			var images []string
+			var positions []TokenPos
+			for token, err := range scanner.All() {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				images = append(images, token.Image())
+				positions = append(positions, token.ResetFlag())
+			}
+			if !reflect.DeepEqual(images, tt.want) {
+				t.Errorf("All() images = %q, want %q", images, tt.want)
+			}
+			if !reflect.DeepEqual(positions, tt.wantPos) {
+				t.Errorf("All() positions = %v, want %v", positions, tt.wantPos)
+			}
+			if !reflect.DeepEqual(scanner.Diagnostics(), tt.wantDiags) {
+				t.Errorf("Diagnostics() = %v, want %v", scanner.Diagnostics(), tt.wantDiags)
+			}
+		})
+	}
+}
+
+func TestScan_InputNormalisationTrivia(t *testing.T) {
+	input := "\uFEFF; header\r\n(role x) ; first\r\n\r\n(role o)\r"
+	tokens, err := ScanAll(strings.NewReader(input), Options{Trivia: true})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	var text strings.Builder
+	for _, token := range tokens {
+		text.WriteString(token.FullText())
+	}
+	if text.String() != input {
+		t.Errorf("FullText() = %q, want %q", text.String(), input)
+	}
+	leading := tokens[0].Trivia.Leading
+	if leading[0] != (TriviaPiece{TRIVIA_BOM, UTF8_BOM}) ||
+		leading[2] != (TriviaPiece{TRIVIA_NEWLINE, "\r\n"}) {
+		t.Errorf("leading trivia = %v", leading)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/intern.go
+
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// A compact identifier for a symbol (the name of a constant, relation or
+// variable) within a Symbols table.  Symbols with the same name have the same
+// ID, so they can be compared without comparing their names.  The zero value
+// is NoSymbol, which is the ID of no name.
+type SymbolID uint32
+
+const NoSymbol SymbolID = 0
+
+// Symbols is an intern table, mapping each distinct name to a SymbolID.  IDs
+// are assigned in the order that names are first interned, starting at 1, so a
+// table populated from the same sources in the same order has the same IDs.
+//
+// A table may be shared by several scanners (see Options.Symbols) and by the
+// representations built from their tokens, and is safe for concurrent use.
+// It serializes to JSON as the array of its names in ID order, so that the IDs
+// can be shared with clients (where the name of ID n is at index n-1).
+type Symbols struct {
+	mutex sync.RWMutex
+	names []string
+	ids   map[string]SymbolID
+}
+
+func NewSymbols() *Symbols {
+	return &Symbols{ids: make(map[string]SymbolID)}
+}
+
+// Returns the ID of the name, adding it to the table if it is not already there.
+func (table *Symbols) Intern(name string) SymbolID {
+	table.mutex.RLock()
+	id, ok := table.ids[name]
+	table.mutex.RUnlock()
+	if ok {
+		return id
+	}
+
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	if id, ok := table.ids[name]; ok {
+		return id
+	}
+	table.names = append(table.names, name)
+	id = SymbolID(len(table.names))
+	table.ids[name] = id
+	return id
+}
+
+// Returns the ID of the name if it has been interned, or NoSymbol if it hasn't.
+func (table *Symbols) Lookup(name string) (SymbolID, bool) {
+	table.mutex.RLock()
+	defer table.mutex.RUnlock()
+	id, ok := table.ids[name]
+	return id, ok
+}
+
+// Returns the name with the indicated ID, or "" if there is no such ID.
+func (table *Symbols) Name(id SymbolID) string {
+	table.mutex.RLock()
+	defer table.mutex.RUnlock()
+	if id == NoSymbol || int(id) > len(table.names) {
+		return ""
+	}
+	return table.names[id-1]
+}
+
+// Returns the number of symbols in the table.
+func (table *Symbols) Len() int {
+	table.mutex.RLock()
+	defer table.mutex.RUnlock()
+	return len(table.names)
+}
+
+// Returns the names of the symbols in order of their IDs.
+func (table *Symbols) Names() []string {
+	table.mutex.RLock()
+	defer table.mutex.RUnlock()
+	return append([]string{}, table.names...)
+}
+
+func (table *Symbols) MarshalJSON() ([]byte, error) {
+	return json.Marshal(table.Names())
+}
+
+// Replaces the contents of the table with the names, which must be distinct.
+func (table *Symbols) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	ids := make(map[string]SymbolID, len(names))
+	for i, name := range names {
+		if _, ok := ids[name]; ok {
+			return fmt.Errorf("duplicate symbol %q in table", name)
+		}
+		ids[name] = SymbolID(i + 1)
+	}
+
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	table.names, table.ids = names, ids
+	return nil
+}
+
+// Returns the SymbolID of an IDENT or VARIABLE token (for a variable, the ID of
+// its name without the sigil) when it was scanned with Options.Symbols, and
+// NoSymbol for any other token.
+func (token Token) Symbol() SymbolID {
+	if symbolic, ok := token.TokenType.(interface{ Symbol() SymbolID }); ok {
+		return symbolic.Symbol()
+	}
+	return NoSymbol
+}
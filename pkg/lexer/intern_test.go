@@ -0,0 +1,128 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/intern_test.go
+
+package lexer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSymbols_Intern(t *testing.T) {
+	table := NewSymbols()
+	cell, mark := table.Intern("cell"), table.Intern("mark")
+	if cell != 1 || mark != 2 {
+		t.Errorf("Intern() = %d, %d, want IDs in insertion order", cell, mark)
+	}
+	if again := table.Intern("cell"); again != cell {
+		t.Errorf("Intern(cell) again = %d, want %d", again, cell)
+	}
+	if table.Name(mark) != "mark" || table.Name(NoSymbol) != "" || table.Name(7) != "" {
+		t.Errorf("Name() does not match the interned names")
+	}
+	if id, ok := table.Lookup("x"); ok || id != NoSymbol {
+		t.Errorf("Lookup(x) = %d, %v before it was interned", id, ok)
+	}
+	if table.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", table.Len())
+	}
+}
+
+func TestSymbols_Concurrent(t *testing.T) {
+	table := NewSymbols()
+	names := []string{"cell", "mark", "control", "noop", "x", "o", "b"}
+	var wait sync.WaitGroup
+	for range 8 {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for _, name := range names {
+				table.Intern(name)
+			}
+		}()
+	}
+	wait.Wait()
+	if table.Len() != len(names) {
+		t.Fatalf("Len() = %d, want %d", table.Len(), len(names))
+	}
+	for _, name := range names {
+		if id, _ := table.Lookup(name); table.Name(id) != name {
+			t.Errorf("Name(Lookup(%s)) = %q", name, table.Name(id))
+		}
+	}
+}
+
+func TestSymbols_JSON(t *testing.T) {
+	table := NewSymbols()
+	for _, name := range []string{"cell", "mark", "x"} {
+		table.Intern(name)
+	}
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `["cell","mark","x"]` {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+
+	decoded := NewSymbols()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Names(), table.Names()) {
+		t.Errorf("decoded Names() = %v, want %v", decoded.Names(), table.Names())
+	}
+	if id, _ := decoded.Lookup("mark"); id != 2 {
+		t.Errorf("decoded Lookup(mark) = %d, want 2", id)
+	}
+	if err := json.Unmarshal([]byte(`["a","a"]`), NewSymbols()); err == nil {
+		t.Errorf("json.Unmarshal() of duplicate names should fail")
+	}
+}
+
+func TestScan_Symbols(t *testing.T) {
+	table := NewSymbols()
+	table.Intern("mark")
+	opts := Options{Symbols: table}
+	tokens, err := ScanAll(strings.NewReader("(<= (legal ?w (mark ?x ?y)) (true (cell ?x ?y b)))"), opts)
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	var got []string
+	for _, token := range tokens {
+		if id := token.Symbol(); id != NoSymbol {
+			got = append(got, table.Name(id))
+		}
+	}
+	want := []string{"w", "mark", "x", "y", "cell", "x", "y", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbol() names = %v, want %v", got, want)
+	}
+	// Keywords are not symbols, and the names are added in order of appearance.
+	if names := table.Names(); !reflect.DeepEqual(names,
+		[]string{"mark", "w", "x", "y", "cell", "b"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	// A second scan shares the table's IDs.
+	more, _ := ScanAll(strings.NewReader("(cell 1 1 b)"), opts)
+	if cell, _ := table.Lookup("cell"); more[1].Symbol() != cell {
+		t.Errorf("Symbol() of cell = %d, want %d", more[1].Symbol(), cell)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/json.go
+
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The canonical JSON representation of tokens and diagnostics, shared with the
+// TypeScript lexer so that the two can be compared token-for-token.  A token is
+//
+//	{"type":"IDENT","image":"cell","line":3,"column":5,"flag":"sentence","span":[40,44]}
+//
+// with its trivia (when scanned with Options.Trivia) as a "trivia" property of
+// "leading" and "trailing" pieces.  The span is a pair of Pos values, which for
+// a source scanned without a File are the byte offsets plus one.
+type tokenJSON struct {
+	Type   string      `json:"type"`
+	Image  string      `json:"image"`
+	Line   uint        `json:"line"`
+	Column uint        `json:"column"`
+	Flag   string      `json:"flag"`
+	Span   [2]Pos      `json:"span"`
+	Trivia *triviaJSON `json:"trivia,omitempty"`
+}
+
+type triviaJSON struct {
+	Leading  []TriviaPiece `json:"leading"`
+	Trailing []TriviaPiece `json:"trailing"`
+}
+
+func (token Token) MarshalJSON() ([]byte, error) {
+	value := tokenJSON{
+		Type:   token.TypeString(),
+		Image:  token.Image(),
+		Line:   token.Line(),
+		Column: token.Column(),
+		Flag:   token.TokenPos.flagName(),
+		Span:   [2]Pos{token.Span.Start, token.Span.End},
+	}
+	if token.TokenType == EOF.TokenType {
+		value.Image = ""
+	}
+	if token.Trivia != nil {
+		value.Trivia = &triviaJSON{
+			Leading:  append([]TriviaPiece{}, token.Trivia.Leading...),
+			Trailing: append([]TriviaPiece{}, token.Trivia.Trailing...),
+		}
+	}
+	return marshalJSON(value)
+}
+
+// Marshals the value without escaping '<', '>' and '&' (which are common in the
+// images of operators), as JavaScript's JSON.stringify() does not escape them.
+func marshalJSON(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte{'\n'}), nil
+}
+
+// The name of the position's flag, as used in the JSON representation.
+func (pos TokenPos) flagName() string {
+	switch pos.flag() {
+	case kTOKENPOS_FLAG_COMMENT:
+		return "comment"
+	case kTOKENPOS_FLAG_SENTENCE:
+		return "sentence"
+	case kTOKENPOS_FLAG_METAL:
+		return "meta"
+	}
+	return "unknown"
+}
+
+func (piece TriviaPiece) MarshalJSON() ([]byte, error) {
+	return marshalJSON(struct {
+		Kind string `json:"kind"`
+		Text string `json:"text"`
+	}{piece.Kind.String(), piece.Text})
+}
+
+func (diag Diagnostic) MarshalJSON() ([]byte, error) {
+	return marshalJSON(struct {
+		Line     uint   `json:"line"`
+		Column   uint   `json:"column"`
+		Span     [2]Pos `json:"span"`
+		Severity string `json:"severity"`
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+	}{diag.Line(), diag.Column(), [2]Pos{diag.Span.Start, diag.Span.End},
+		diag.Severity.String(), diag.Code, diag.Message})
+}
+
+// Writes the tokens and diagnostics of a source as a JSON object, with one token
+// (or diagnostic) per line so that the output of two lexers can be diffed:
+//
+//	{
+//	  "file": "janken.ggd",
+//	  "dialect": "GEL",
+//	  "tokens": [
+//	    {"type":"KEYWORD","image":"role",...},
+//	    ...
+//	  ],
+//	  "diagnostics": []
+//	}
+func WriteTokensJSON(output io.Writer, name string, dialect Dialect,
+	tokens []Token, diagnostics []Diagnostic) error {
+	var text strings.Builder
+	header, err := marshalJSON(name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&text, "{\n  \"file\": %s,\n  \"dialect\": %q,\n", header, dialect)
+
+	writeList := func(key string, count int, item func(int) any) error {
+		fmt.Fprintf(&text, "  %q: [", key)
+		for i := range count {
+			line, err := marshalJSON(item(i))
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				text.WriteString(",")
+			}
+			fmt.Fprintf(&text, "\n    %s", line)
+		}
+		if count > 0 {
+			text.WriteString("\n  ")
+		}
+		text.WriteString("]")
+		return nil
+	}
+	if err := writeList("tokens", len(tokens), func(i int) any { return tokens[i] }); err != nil {
+		return err
+	}
+	text.WriteString(",\n")
+	if err := writeList("diagnostics", len(diagnostics),
+		func(i int) any { return diagnostics[i] }); err != nil {
+		return err
+	}
+	text.WriteString("\n}\n")
+	_, err = io.WriteString(output, text.String())
+	return err
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/json_test.go
+
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden files in testdata/")
+
+func TestToken_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  []string
+	}{
+		{"sentence", "(<= x)", Options{}, []string{
+			`{"type":"OPEN_PAREN","image":"(","line":1,"column":1,"flag":"sentence","span":[1,2]}`,
+			`{"type":"ARROW_LD","image":"<=","line":1,"column":2,"flag":"sentence","span":[2,4]}`,
+			`{"type":"IDENT","image":"x","line":1,"column":5,"flag":"sentence","span":[5,6]}`,
+			`{"type":"CLOSE_PAREN","image":")","line":1,"column":6,"flag":"sentence","span":[6,7]}`,
+		}},
+		{"comment", "; \"quoted\" & more", Options{}, []string{
+			`{"type":"COMMENT","image":"; \"quoted\" & more","line":1,"column":1,"flag":"comment","span":[1,18]}`,
+		}},
+		{"trivia", "x ; note\n", Options{Trivia: true}, []string{
+			`{"type":"IDENT","image":"x","line":1,"column":1,"flag":"sentence","span":[1,2],` +
+				`"trivia":{"leading":[],"trailing":[{"kind":"SPACE","text":" "},{"kind":"COMMENT","text":"; note"}]}}`,
+			`{"type":"EOF","image":"","line":0,"column":0,"flag":"unknown","span":[0,0],` +
+				`"trivia":{"leading":[{"kind":"NEWLINE","text":"\n"}],"trailing":[]}}`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(tt.input), tt.opts)
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			if len(tokens) != len(tt.want) {
+				t.Fatalf("ScanAll() = %v", tokens)
+			}
+			for i, token := range tokens {
+				got, err := json.Marshal(token)
+				if err != nil {
+					t.Fatalf("json.Marshal() error = %v", err)
+				}
+				// encoding/json escapes '<', '>' and '&' unless the encoder is told not to.
+				var compact bytes.Buffer
+				json.Compact(&compact, []byte(tt.want[i]))
+				if !json.Valid(got) || string(got) != jsonEscapeHTML(compact.String()) {
+					t.Errorf("json.Marshal() = %s, want %s", got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func jsonEscapeHTML(text string) string {
+	var escaped bytes.Buffer
+	json.HTMLEscape(&escaped, []byte(text))
+	return escaped.String()
+}
+
+func TestWriteTokensJSON(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("(role\n\"x"), Options{})
+	var tokens []Token
+	for token, err := range scanner.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	var output bytes.Buffer
+	if err := WriteTokensJSON(&output, "test.kif", KIF, tokens, scanner.Diagnostics()); err != nil {
+		t.Fatalf("WriteTokensJSON() error = %v", err)
+	}
+	want := `{
+  "file": "test.kif",
+  "dialect": "KIF",
+  "tokens": [
+    {"type":"OPEN_PAREN","image":"(","line":1,"column":1,"flag":"sentence","span":[1,2]},
+    {"type":"KEYWORD","image":"role","line":1,"column":2,"flag":"sentence","span":[2,6]},
+    {"type":"UNEXPECTED","image":"\"x","line":2,"column":1,"flag":"sentence","span":[7,9]}
+  ],
+  "diagnostics": [
+    {"line":2,"column":1,"span":[7,9],"severity":"error","code":"unterminated-string","message":"unterminated string"},
+    {"line":1,"column":1,"span":[1,2],"severity":"error","code":"unclosed-paren","message":"unclosed '(' at end of input"}
+  ]
+}
+`
+	if output.String() != want {
+		t.Errorf("WriteTokensJSON() =\n%s\nwant\n%s", output.String(), want)
+	}
+}
+
+// The golden corpus of the examples' tokens, for comparing with the output of
+// other lexers.  Run with -update to regenerate the files in testdata/tokens.
+func TestGolden_Examples(t *testing.T) {
+	const examples = "../../examples"
+	var paths []string
+	err := filepath.WalkDir(examples, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Ext(path) == ".ggd" {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil || len(paths) == 0 {
+		t.Skipf("no examples found (%v)", err)
+	}
+	for _, path := range paths {
+		// Golden files mirror the examples' subdirectories, while the file name
+		// within the JSON is its base name, as `gelc tokens --json` writes it.
+		rel, _ := filepath.Rel(examples, path)
+		name := filepath.Base(path)
+		t.Run(filepath.ToSlash(rel), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanner := NewBytesScanner(source, Options{Dialect: GEL})
+			var tokens []Token
+			for token, err := range scanner.All() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				tokens = append(tokens, token)
+			}
+			var output bytes.Buffer
+			if err := WriteTokensJSON(&output, name, GEL, tokens, scanner.Diagnostics()); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "tokens", strings.TrimSuffix(rel, ".ggd")+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, output.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(output.Bytes(), want) {
+				t.Errorf("tokens of %s differ from %s (run with -update if intended)", rel, golden)
+			}
+		})
+	}
+}
@@ -16,24 +16,31 @@
 
 package lexer
 
-import "unicode"
+import (
+	"unicode"
+	"unicode/utf8"
+)
 
+// Reads a keyword or identifier (or, in HRF, a capitalized variable).  Keywords
+// share their dialect's TokenType and identifiers are interned by the scanner,
+// so only the first occurrence of a name allocates.
 func (reader *lexerState) readKeywordOrIdent() Token {
-	var r rune
-	reader.cursor, r = reader.cursor.FirstRune(reader.input)
-	for !reader.cursor.HasError() && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
-		reader.cursor, r = reader.cursor.NextRune(reader.input)
+	pos := reader.begin()
+	reader.skipWhile(isIdentRune)
+	image := reader.image()
+	if keyword, ok := reader.dialect.keywords[string(image)]; ok {
+		return Token{TokenPos: pos, TokenType: keyword}
 	}
-	cursor := reader.cursor
-	var image string
-	if cursor.HasError() {
-		reader.cursor, image = cursor.ConsumeAll()
-	} else {
-		reader.cursor, image = cursor.ConsumeExceptFinal()
+	if first, _ := utf8.DecodeRune(image); reader.dialect.capitalVariables &&
+		unicode.IsUpper(first) {
+		return Token{TokenPos: pos, TokenType: reader.variable(image)}
 	}
-	var token Token = KeywordAt(image, cursor.Pos())
+	return Token{TokenPos: pos, TokenType: reader.ident(image)}
+}
 
-	return token
+// Identifiers and keywords are composed of letters, digits and underscores.
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
 }
 
 // All keywords are given the KEYWORD token type.
@@ -54,39 +61,17 @@ func (tok KeywordToken) Image() string {
 // Constructs a Token instance pointing to the singular KeywordToken instance
 // for the specific keyword.
 func (tok KeywordToken) At(pos TokenPos) Token {
-	return Token{pos, tok}
+	return Token{TokenPos: pos, TokenType: tok}
 }
 
-var keywords map[string]KeywordToken = make(map[string]KeywordToken)
-
-func defineKeyword(image string) { keywords[image] = KeywordToken{image} }
-func init() {
-	// Special relations defined for the semantics of GDL.
-	defineKeyword("role")
-	defineKeyword("legal")
-	defineKeyword("next")
-	defineKeyword("does")
-	defineKeyword("goal")
-	defineKeyword("terminal")
-
-	// Additional special relations for GDL-II.
-	defineKeyword("sees")
-	defineKeyword("random")
-
-	// These could be inferred or defined in terms of other rules.
-	defineKeyword("init")
-	defineKeyword("input")
-
-	// Some boolean relations that GDL assumes existence of.
-	defineKeyword("true")
-	defineKeyword("or")
-	defineKeyword("and")
-	defineKeyword("not")
-}
+// The keywords of GDL (KIF), which is the default dialect.
+// See also [dialect.go] for the keywords of each dialect.
+var keywords map[string]TokenType = dialects[KIF].keywords
 
+// Constructs a KEYWORD token if the image is a (KIF) keyword, otherwise IDENT.
 func KeywordAt(image string, pos TokenPos) Token {
 	if keyword, ok := keywords[image]; ok {
-		return keyword.At(pos)
+		return Token{TokenPos: pos, TokenType: keyword}
 	}
 	return Identifier(image, pos)
 }
\ No newline at end of file
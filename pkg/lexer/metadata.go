@@ -0,0 +1,188 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/metadata.go
+
+package lexer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Game metadata is written in the header of a file, before its first sentence,
+// as line comments whose comment marker is immediately followed by a '!'.  Each
+// meta line is a `key: value` property.  Because they are comments, the rules
+// can still be read by other GDL tools, e.g. in KIF and GEL:
+//
+//	;! title: Tic-Tac-Toe              %%! title: Tic-Tac-Toe
+//	;! players: 2                      %%! players: 2
+//	;! author: Jane Doe                %%! author: Jane Doe
+//	(role x)                           role x { marker: "X" }
+//
+// Ordinary comments may be interleaved with the meta lines.  After the header,
+// comments beginning with '!' are treated as ordinary comments.
+const META_MARKER = "!"
+
+// Produces a META token from the (already consumed) comment image.  A meta line
+// that is not a `key: value` property is reported as a Diagnostic, with its
+// text as the value and an empty key.
+func (reader *lexerState) readMeta(image, marker string, pos TokenPos) Token {
+	text := strings.TrimSpace(image[len(marker)+len(META_MARKER):])
+	key, value, found := strings.Cut(text, ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		reader.errorAt(pos, CODE_MALFORMED_META, "meta line is not a 'key: value' property")
+		key, value = "", text
+	}
+	return Token{TokenPos: pos, TokenType: &MetaToken{image, key, value}}
+}
+
+// A token representing a meta line (a property of the game's metadata).
+type MetaToken struct {
+	image string
+	key   string
+	value string
+}
+
+// Constructs a META token from the raw image and its parsed key and value.
+func MetaLine(image, key, value string, pos TokenPos) Token {
+	return Token{TokenPos: pos, TokenType: &MetaToken{image, key, value}}
+}
+func (meta *MetaToken) TypeString() string { return "META" }
+func (meta *MetaToken) Image() string      { return meta.image }
+
+// Returns the (lowercase) property name of the meta line.
+func (meta *MetaToken) Key() string { return strings.ToLower(meta.key) }
+
+// Returns the property value of the meta line, without surrounding spaces.
+func (meta *MetaToken) Value() string { return meta.value }
+
+// The metadata of a game, as read from the header of its rules.
+type Metadata struct {
+	Title  string
+	Status string
+
+	// The range of players the game accepts.  These are zero if not specified.
+	// A single `players: 2` (or a range, `players: 2-4`) sets both of them.
+	MinPlayers int
+	MaxPlayers int
+
+	// From any `author:` lines or a comma-separated list in `authors:`.
+	Authors []string
+	License string
+	Version string
+
+	// Properties in the meta block other than those listed above.
+	Extra map[string]string
+}
+
+// Reads the metadata in the header of a rules file without reading (or parsing)
+// the remainder of the rules.  The dialect is determined from the first comment
+// marker found in the file.  An error is returned if the input could not be
+// read or if a known property has an invalid value or a meta line is malformed.
+func ReadMetadata(input io.Reader) (Metadata, error) {
+	buffered := bufio.NewReader(input)
+	scanner := NewScanner(buffered, Options{Dialect: sniffDialect(buffered)})
+
+	metadata := Metadata{Extra: make(map[string]string)}
+	for token, err := range scanner.All() {
+		if err != nil {
+			return metadata, err
+		}
+		if IsComment(token.TokenPos) {
+			continue
+		}
+		meta, ok := token.TokenType.(*MetaToken)
+		if !ok {
+			break
+		}
+		if meta.Key() == "" {
+			continue
+		}
+		if err := metadata.set(meta.Key(), meta.Value()); err != nil {
+			return metadata, fmt.Errorf("%d:%d: %s", token.Line(), token.Column(), err)
+		}
+	}
+	if diagnostics := scanner.Diagnostics(); len(diagnostics) > 0 {
+		return metadata, diagnostics[0]
+	}
+	return metadata, nil
+}
+
+// Determines the dialect from the first comment marker in the input, if any.
+// The input is not advanced.  Defaults to KIF if there is no leading comment.
+// A byte order mark is skipped, as the lexer skips it.
+func sniffDialect(input *bufio.Reader) Dialect {
+	head, _ := input.Peek(512)
+	head = bytes.TrimPrefix(head, []byte(UTF8_BOM))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case bytes.HasPrefix(head, []byte("%%")):
+		return GEL
+	case bytes.HasPrefix(head, []byte("%")):
+		return HRF
+	}
+	return KIF
+}
+
+// Assigns a property of the metadata from its key and (string) value.
+func (metadata *Metadata) set(key, value string) error {
+	var err error
+	switch key {
+	case "title":
+		metadata.Title = value
+	case "status":
+		metadata.Status = value
+	case "players":
+		low, high, isRange := strings.Cut(value, "-")
+		if !isRange {
+			high = low
+		}
+		if metadata.MinPlayers, err = parsePlayers(key, low); err == nil {
+			metadata.MaxPlayers, err = parsePlayers(key, high)
+		}
+	case "min_players":
+		metadata.MinPlayers, err = parsePlayers(key, value)
+	case "max_players":
+		metadata.MaxPlayers, err = parsePlayers(key, value)
+	case "author":
+		metadata.Authors = append(metadata.Authors, value)
+	case "authors":
+		for _, author := range strings.Split(value, ",") {
+			if author = strings.TrimSpace(author); author != "" {
+				metadata.Authors = append(metadata.Authors, author)
+			}
+		}
+	case "license":
+		metadata.License = value
+	case "version":
+		metadata.Version = value
+	default:
+		metadata.Extra[key] = value
+	}
+	return err
+}
+
+func parsePlayers(key, value string) (int, error) {
+	count, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("invalid %s value %q", key, value)
+	}
+	return count, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/metadata_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_Meta(t *testing.T) {
+	input := "%%! title: Tic-Tac-Toe\n%% an ordinary comment\n%%! players: 2\n" +
+		"role x\n%%! title: not meta\n"
+	tokens, err := ScanAll(strings.NewReader(input), Options{Dialect: GEL})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	for i := range tokens {
+		tokens[i].Span = Span{} // Spans are tested in TestScan_Spans.
+	}
+	want := []Token{
+		MetaLine("%%! title: Tic-Tac-Toe", "title", "Tic-Tac-Toe",
+			NewTokenPos(1, 1).InMetaBlock()),
+		LineComment("%% an ordinary comment", NewTokenPos(2, 1).InComment()),
+		MetaLine("%%! players: 2", "players", "2", NewTokenPos(3, 1).InMetaBlock()),
+		KeywordToken{"role"}.At(NewTokenPos(4, 1).InSentence()),
+		Identifier("x", NewTokenPos(4, 6).InSentence()),
+		LineComment("%%! title: not meta", NewTokenPos(5, 1).InComment()),
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("ScanAll()\n  got %v\n want %v", tokens, want)
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Metadata
+		wantErr string
+	}{
+		{"none", "(role x)", Metadata{Extra: map[string]string{}}, ""},
+		{"kif", ";! title: Tic-Tac-Toe\n;! status: beta\n;! players: 2\n" +
+			";! author: Ada\n;! author: Grace\n;! license: CC-BY\n;! version: 1.2\n" +
+			"(role x)\n;! title: ignored\n",
+			Metadata{
+				Title: "Tic-Tac-Toe", Status: "beta", MinPlayers: 2, MaxPlayers: 2,
+				Authors: []string{"Ada", "Grace"}, License: "CC-BY", Version: "1.2",
+				Extra: map[string]string{},
+			}, ""},
+		{"gel", "\n%%! Title: Janken\n%% Rock-Paper-Scissors\n%%! players: 2-4\n" +
+			"%%! authors: Ada, Grace\n%%! wiki: janken\nrole left, right",
+			Metadata{
+				Title: "Janken", MinPlayers: 2, MaxPlayers: 4,
+				Authors: []string{"Ada", "Grace"},
+				Extra:   map[string]string{"wiki": "janken"},
+			}, ""},
+		{"gel with BOM", UTF8_BOM + "%%! title: Janken\nrole left, right",
+			Metadata{Title: "Janken", Extra: map[string]string{}}, ""},
+		{"hrf", "%! min_players: 1\n%! max_players: 3\nrole(x)",
+			Metadata{MinPlayers: 1, MaxPlayers: 3, Extra: map[string]string{}}, ""},
+		{"invalid players", ";! players: two\n",
+			Metadata{Extra: map[string]string{}}, `1:1: invalid players value "two"`},
+		{"malformed", ";! just a note\n(role x)",
+			Metadata{Extra: map[string]string{}},
+			"1:1: meta line is not a 'key: value' property"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMetadata(strings.NewReader(tt.input))
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("ReadMetadata() error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMetadata()\n  got %+v\n want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,11 +18,83 @@ package lexer
 
 import (
 	"io"
+	"iter"
+	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 )
 
+// Public interface for reading a stream of tokens synchronously, one at a time.
+// This is the primary interface to the lexer, the channel-based TokenReader is
+// an adapter on top of it for consumers that prefer to receive on a channel.
+type TokenScanner interface {
+	// Reads and returns the next token.  When the end of input is reached the EOF
+	// token is returned along with io.EOF, and any other error from the input is
+	// returned (also with EOF) after all tokens read before it have been scanned.
+	// Once io.EOF has been returned, every following call returns it again.  Any
+	// other error is returned once, and the next call will try the input again.
+	Scan() (Token, error)
+
+	// Iterates over the tokens remaining in the input.  The EOF token is not
+	// yielded (except when scanning trivia, see Options.Trivia); iteration ends
+	// quietly at io.EOF.  If any other error is found it
+	// is yielded with the EOF token as the final (token, error) pair.
+	All() iter.Seq2[Token, error]
+
+	// Returns the diagnostics for malformed input found so far.  Scanning resumes
+	// after malformed input, producing UNEXPECTED tokens for it, so these are not
+	// returned by Scan() and should be checked after (or while) scanning.
+	Diagnostics() []Diagnostic
+}
+
+// Constructor function for a lexer-based token scanner.  The options select
+// which dialect is being scanned, see [Options].  If the input is also an
+// io.Reader (as strings.Reader and bufio.Reader are) it is read as bytes.
+func NewScanner(input io.RuneReader, opts Options) TokenScanner {
+	if reader, ok := input.(io.Reader); ok {
+		return newLexer(newInput(reader, opts), opts)
+	}
+	return newLexer(newInput(runeInput{input}, opts), opts)
+}
+
+// Constructor function for a token scanner over an in-memory source, such as a
+// message received by the GM.  The data is scanned in place, without copying,
+// and must not be modified while scanning.
+func NewBytesScanner(data []byte, opts Options) TokenScanner {
+	return newLexer(newBytesInput(data, opts), opts)
+}
+
+func newLexer(in input, opts Options) *lexerState {
+	reader := &lexerState{
+		input:    in,
+		dialect:  opts.dialect(),
+		trivia:   opts.Trivia,
+		interned: acquireInterned(opts.Symbols),
+	}
+	reader.opened = reader.openedBuf[:0]
+	return reader
+}
+
+// Scans the entire input, returning all of its tokens (not including EOF).
+// Any error other than io.EOF is returned along with the tokens read before it.
+func ScanAll(input io.RuneReader, opts Options) ([]Token, error) {
+	tokens := make([]Token, 0, 32)
+	for token, err := range NewScanner(input, opts).All() {
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
 // Public interface for reading a stream of tokens, sending them to a channel.
 // See also ReadAll(reader) which provides a simpler interface for full reads.
+//
+// The channel is written to by the same goroutine that calls NextToken(), so
+// unless the channel is buffered the receiver must be running in another
+// goroutine.  Callers that do not need a channel should use TokenScanner.
 type TokenReader interface {
 	// Reads the next token, sending it to output, returning error or nil.  If an
 	// io.EOF error was encountered it is returned here as well.
@@ -36,9 +108,9 @@ type TokenReader interface {
 	TokenReceiver() <-chan Token
 }
 
-// Constructor function for a lexer-based token reader.
+// Constructor function for a lexer-based token reader, for the KIF dialect.
 func NewTokenReader(input io.RuneReader, output chan Token) TokenReader {
-	return &lexerState{input, output, NewCursor()}
+	return &channelReader{NewScanner(input, Options{}), output, false}
 }
 
 // Repeatedly calls `NextToken()` until either the enf of file (EOF) is reached
@@ -58,70 +130,320 @@ func ReadAll(reader TokenReader) error {
 	return nil
 }
 
-// Backing store for the TokenReader's state.  Alternative implementations of
-// TokenReader are possible (e.g., reading from a token buffer, generating for
-// token macros, mocking in tests, extending with modules, etc.) so the naming
-// indicates the particular use of this state:
-// a Lexer producing tokens (from input RuneReader, to output Token channel).
-type lexerState struct {
-	input  io.RuneReader
-	output chan Token
-	cursor Cursor
+// Adapts a TokenScanner to the TokenReader interface, forwarding each scanned
+// token to the output channel and closing it when the scanner is exhausted.
+type channelReader struct {
+	scanner TokenScanner
+	output  chan Token
+	closed  bool
 }
 
 // Simply returns the already-created channel.
-func (reader *lexerState) TokenReceiver() <-chan Token {
+func (reader *channelReader) TokenReceiver() <-chan Token {
 	return reader.output
 }
 
-// Checks the cursor's error/EOF status and closes the channel if an error is
-// found.  Sends an EOF token before closing, when an io.EOF is encountered.
-// If a non-nil value is passed as argument, that is sent before sending EOF.
-func (reader *lexerState) consumeEOF(token *Token) {
-	if !reader.cursor.HasError() {
-		if token != nil && *token != EOF {
-			reader.output <- *token
-		}
-		if reader.cursor.IsEOF() {
+// Scans the next token and sends it to the Token chan, possibly closing it.
+// The EOF token is sent before closing only when the input ended with io.EOF.
+func (reader *channelReader) NextToken() error {
+	token, err := reader.scanner.Scan()
+	if err == nil {
+		reader.output <- token
+		return nil
+	}
+	if !reader.closed {
+		if err == io.EOF {
 			reader.output <- EOF
 		}
 		close(reader.output)
+		reader.closed = true
+	}
+	return err
+}
+
+// Backing store for the TokenScanner's state.  Alternative implementations of
+// TokenScanner are possible (e.g., reading from a token buffer, generating for
+// token macros, mocking in tests, extending with modules, etc.) so the naming
+// indicates the particular use of this state:
+// a Lexer producing tokens (from input bytes, see [input.go]).
+type lexerState struct {
+	input
+	dialect *dialect
+	*interned
+
+	diagnostics []Diagnostic
+	// The currently unclosed '(' tokens, its length is the nesting depth.
+	opened []Token
+	// Backing for opened, so that typical nesting does not allocate.
+	openedBuf [8]Token
+	// Becomes true at the first token that is not a comment or meta line.
+	inBody bool
+
+	// Whether to attach trivia to the tokens, see Options.Trivia.
+	trivia bool
+	// Tokens that were read ahead (while looking for trivia), to be scanned next.
+	lookahead []Token
+	// Tokens that were scanned ahead (while looking for the declaration after a
+	// doc comment), to be returned next by Scan().
+	queue []scanned
+	// The line of the token most recently returned by Scan().
+	lastLine uint
+}
+
+// Each scanner interns the images of its identifiers, variables and integers,
+// so that a name repeated throughout a game (or a message) shares one TokenType
+// instance and is only copied out of the input the first time it is seen.
+// Lookups index the maps by the bytes of the image, which does not allocate.
+// The names are also interned in the shared Symbols table, if there is one.
+//
+// The tables are pooled: when a scanner reaches the end of its input its table
+// is reused by a later scanner, along with the tokens interned in it.  The GM
+// scans the same few names in the messages of every turn, which then allocate
+// nothing beyond the scanner itself.  The interned TokenTypes are never
+// modified, so sharing them between scanners (and goroutines) is safe.
+type interned struct {
+	symbols   *Symbols
+	idents    map[string]*identToken
+	variables map[string]*VariableToken
+	integers  map[string]*IntegerToken
+}
+
+// Tables holding more names than this are not pooled, so that scanning a large
+// file does not keep all of its names alive for the scanners that follow it.
+const maxPooledNames = 4096
+
+var internedPool = sync.Pool{New: func() any {
+	return &interned{
+		idents:    make(map[string]*identToken),
+		variables: make(map[string]*VariableToken),
+		integers:  make(map[string]*IntegerToken),
+	}
+}}
+
+// Returns a table from the pool, cleared if it was used with other Symbols.
+func acquireInterned(symbols *Symbols) *interned {
+	table := internedPool.Get().(*interned)
+	if table.symbols != symbols {
+		clear(table.idents)
+		clear(table.variables)
+		clear(table.integers)
+		table.symbols = symbols
+	}
+	return table
+}
+
+// Returns the table to the pool.  The scanner must not use it afterward.
+func releaseInterned(table *interned) {
+	if len(table.idents)+len(table.variables)+len(table.integers) <= maxPooledNames {
+		internedPool.Put(table)
+	}
+}
+
+func (table *interned) ident(image []byte) *identToken {
+	if ident, ok := table.idents[string(image)]; ok {
+		return ident
 	}
+	ident := &identToken{name: string(image)}
+	if table.symbols != nil {
+		ident.symbol = table.symbols.Intern(ident.name)
+	}
+	table.idents[ident.name] = ident
+	return ident
 }
 
-// Reads the next token, sending it to the Token chan, possibly closing it.
-func (reader *lexerState) NextToken() error {
-	if reader.cursor.HasError() {
-		return reader.cursor.ErrorValue()
+func (table *interned) variable(image []byte) *VariableToken {
+	if variable, ok := table.variables[string(image)]; ok {
+		return variable
+	}
+	variable := Variable(string(image), 0).TokenType.(*VariableToken)
+	if table.symbols != nil {
+		variable.symbol = table.symbols.Intern(variable.name)
 	}
+	table.variables[variable.image] = variable
+	return variable
+}
 
-	var r rune
-	reader.cursor, r = reader.cursor.FirstRune(reader.input)
-	if reader.cursor.HasError() {
-		reader.consumeEOF(nil)
-		return reader.cursor.ErrorValue()
+func (table *interned) integer(image []byte) *IntegerToken {
+	if integer, ok := table.integers[string(image)]; ok {
+		return integer
 	}
+	integer := Integer(string(image), 0).TokenType.(*IntegerToken)
+	table.integers[integer.image] = integer
+	return integer
+}
+
+// The result of a call to scanToken(), for queueing.  A comment that is queued
+// has already been determined to be a doc comment or not, except for the token
+// that followed the block, which may begin the next block of comments.
+type scanned struct {
+	token   Token
+	err     error
+	decided bool
+}
+
+// Reads the next token, returning EOF and the error once input is exhausted.
+func (reader *lexerState) Scan() (Token, error) {
+	var token Token
+	var err error
+	decided := false
+	if len(reader.queue) > 0 {
+		next := reader.queue[0]
+		reader.queue = reader.queue[1:]
+		token, err, decided = next.token, next.err, next.decided
+	} else {
+		token, err = reader.scanToken()
+	}
+	if err == nil && !decided && reader.startsDocComment(token) {
+		token = reader.readDocComment(token)
+	}
+	if err == nil {
+		reader.lastLine = token.Line()
+	}
+	return token, err
+}
+
+// Scans the next token, with its trivia if scanning trivia.
+func (reader *lexerState) scanToken() (Token, error) {
+	if reader.trivia {
+		return reader.scanWithTrivia()
+	}
+	token, err := reader.nextToken()
+	if err != nil {
+		return token, err
+	}
+	return reader.trackSentence(token), nil
+}
+
+// Reads the next token from the lookahead or from input.  The token's span is
+// set (along with the spans of any diagnostics reported while reading it), but
+// not its sentence flags.
+func (reader *lexerState) nextToken() (Token, error) {
+	if len(reader.lookahead) > 0 {
+		token := reader.lookahead[0]
+		reader.lookahead = reader.lookahead[1:]
+		return token, nil
+	}
+	reader.skipBOM()
+	reader.skipWhile(unicode.IsSpace)
+	r, size := reader.peek()
+	if size == 0 {
+		return reader.endOfInput()
+	}
+	start := reader.offset()
+	reported := len(reader.diagnostics)
 
 	var token Token
 	switch {
+	case r == utf8.RuneError && size == 1:
+		token = reader.readInvalid()
+	case r == RUNE_DOUBLE_QUOTE:
+		token = reader.readString()
+	case r == RUNE_MINUS && reader.peekDigit():
+		token = reader.readNumber()
+	case strings.ContainsRune(reader.dialect.sigils, r):
+		token = reader.readVariable()
+	case unicode.IsLetter(r), r == '_':
+		token = reader.readKeywordOrIdent()
 	case unicode.IsPunct(r), unicode.IsSymbol(r):
 		token = reader.readOperator()
 	case unicode.IsDigit(r):
 		token = reader.readNumber()
-	case unicode.IsLetter(r):
-		token = reader.readKeywordOrIdent()
 	default:
-		token = reader.consumeUnexpectedToken()
+		token = reader.readInvalid()
+	}
+
+	token.Span = reader.span(start, reader.offset())
+	for i := reported; i < len(reader.diagnostics); i++ {
+		if reader.diagnostics[i].Span == (Span{}) {
+			reader.diagnostics[i].Span = token.Span
+		}
+	}
+	// Invalid UTF-8 is reported wherever it is, including in strings and comments.
+	for _, invalid := range reader.takeInvalid() {
+		reader.errorAt(invalid.pos, CODE_INVALID_UTF8, "invalid UTF-8")
+		reader.diagnostics[len(reader.diagnostics)-1].Span =
+			reader.span(invalid.start, invalid.end)
+	}
+	return token, nil
+}
+
+// Returns EOF with the input's error.  At the end of input any unclosed
+// sentences are reported (and the interned table released, as no more tokens
+// will be read), while other errors are cleared from the input so that it is
+// read again by the next call to Scan().
+func (reader *lexerState) endOfInput() (Token, error) {
+	err := reader.err
+	if err == io.EOF {
+		reader.closeSentences()
+		if reader.interned != nil {
+			releaseInterned(reader.interned)
+			reader.interned = nil
+		}
+	} else {
+		reader.err = nil
+	}
+	return EOF, err
+}
+
+// Converts a range of byte offsets into a Span within the scanner's File.
+func (reader *lexerState) span(start, end int) Span {
+	if reader.file != nil {
+		return Span{reader.file.Pos(start), reader.file.Pos(end)}
 	}
+	return Span{Pos(start + 1), Pos(end + 1)}
+}
 
-	reader.consumeEOF(&token)
-	return reader.cursor.ErrorValue()
+// Iterates over Scan() results until io.EOF or another error is returned.
+func (reader *lexerState) All() iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		for {
+			token, err := reader.Scan()
+			if err == io.EOF {
+				if token.Trivia != nil {
+					yield(token, nil)
+				}
+				return
+			}
+			if !yield(token, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Reads a run of characters that cannot begin any token (or are not valid UTF-8)
+// as a single UnexpectedToken, reporting it once.  Scanning resumes at the next
+// space or character that could begin a token.  Invalid UTF-8 is reported by
+// nextToken(), with the offsets of the invalid bytes.
+func (reader *lexerState) readInvalid() Token {
+	pos := reader.begin()
+	invalid := reader.atInvalid()
+	first := reader.next()
+	for {
+		r, size := reader.peek()
+		if size == 0 || unicode.IsSpace(r) || reader.startsToken(r, size) {
+			break
+		}
+		reader.advance(r, size)
+	}
+	if !invalid {
+		reader.errorAt(pos, CODE_UNEXPECTED_CHAR, "unexpected character %q", first)
+	}
+	return UnexpectedToken(string(reader.image()), pos)
+}
+
+// Returns true if the rune would be scanned as the beginning of a token.
+func (reader *lexerState) startsToken(r rune, size int) bool {
+	if r == utf8.RuneError && size == 1 {
+		return false
+	}
+	return r == RUNE_DOUBLE_QUOTE || strings.ContainsRune(reader.dialect.sigils, r) ||
+		unicode.IsLetter(r) || r == '_' ||
+		unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsDigit(r)
 }
 
-// Consumes what remains in the cursor's buffer as an UnexpectedToken{...}.
-func (reader *lexerState) consumeUnexpectedToken() Token {
-	pos := reader.cursor.Pos()
-	var image string
-	reader.cursor, image = reader.cursor.ConsumeAll()
-	return UnexpectedToken(image, pos)
+// Consumes the next rune as (the end of) an UnexpectedToken{...} at pos.
+func (reader *lexerState) consumeUnexpectedToken(pos TokenPos) Token {
+	reader.next()
+	return UnexpectedToken(string(reader.image()), pos)
 }
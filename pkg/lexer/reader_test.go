@@ -0,0 +1,140 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/reader_test.go
+
+package lexer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Reads runes from a string and then returns a non-EOF error.
+type failingReader struct {
+	input *strings.Reader
+	err   error
+}
+
+func (fr failingReader) ReadRune() (rune, int, error) {
+	r, size, err := fr.input.ReadRune()
+	if err == io.EOF {
+		return 0, 0, fr.err
+	}
+	return r, size, err
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		images []string
+	}{
+		{"empty", "", []string{}},
+		{"only spaces", "  \n ", []string{}},
+		{"sentence", "(role x)", []string{"(", "role", "x", ")"}},
+		{"trailing space", "(role x) \n", []string{"(", "role", "x", ")"}},
+		{"ends on ident", "(<= terminal", []string{"(", "<=", "terminal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{})
+			for _, want := range tt.images {
+				token, err := scanner.Scan()
+				if err != nil {
+					t.Fatalf("Scan() error = %v, wanted token %s", err, want)
+				}
+				if token.Image() != want {
+					t.Errorf("Scan() = %v, want image %s", token, want)
+				}
+			}
+			for range 2 {
+				token, err := scanner.Scan()
+				if err != io.EOF || token != EOF {
+					t.Errorf("Scan() = (%v, %v), want (EOF, io.EOF)", token, err)
+				}
+			}
+		})
+	}
+}
+
+func TestScan_Error(t *testing.T) {
+	oops := errors.New("oops")
+	scanner := NewScanner(failingReader{strings.NewReader("(next x"), oops}, Options{})
+	tokens, err := ScanAll(failingReader{strings.NewReader("(next x"), oops}, Options{})
+	if err != oops {
+		t.Errorf("ScanAll() error = %v, want %v", err, oops)
+	}
+	if len(tokens) != 3 {
+		t.Errorf("ScanAll() produced %d tokens before the error, want 3", len(tokens))
+	}
+
+	for range 3 {
+		if _, err := scanner.Scan(); err != nil {
+			t.Fatalf("Scan() error = %v before end of input", err)
+		}
+	}
+	for range 2 {
+		token, err := scanner.Scan()
+		if err != oops || token != EOF {
+			t.Errorf("Scan() = (%v, %v), want (EOF, %v)", token, err, oops)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	images := []string{}
+	for token, err := range NewScanner(strings.NewReader("(does ?p 42)"), Options{}).All() {
+		if err != nil {
+			t.Fatalf("All() yielded error %v", err)
+		}
+		images = append(images, token.Image())
+	}
+	want := "( does ?p 42 )"
+	if got := strings.Join(images, " "); got != want {
+		t.Errorf("All() yielded %q, want %q", got, want)
+	}
+
+	// Breaking out of the loop early leaves the remaining tokens in the scanner.
+	scanner := NewScanner(strings.NewReader("(does ?p 42)"), Options{})
+	for range scanner.All() {
+		break
+	}
+	if token, _ := scanner.Scan(); token.Image() != "does" {
+		t.Errorf("Scan() after break = %v, want does", token)
+	}
+}
+
+func TestNewTokenReader(t *testing.T) {
+	// A buffered channel lets a single goroutine drive and drain the reader.
+	output := make(chan Token, 8)
+	reader := NewTokenReader(strings.NewReader("(role x)"), output)
+	if err := ReadAll(reader); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	images := []string{}
+	for token := range reader.TokenReceiver() {
+		images = append(images, token.TypeString())
+	}
+	want := "OPEN_PAREN KEYWORD IDENT CLOSE_PAREN EOF"
+	if got := strings.Join(images, " "); got != want {
+		t.Errorf("TokenReceiver() sent %q, want %q", got, want)
+	}
+	if err := reader.NextToken(); err != io.EOF {
+		t.Errorf("NextToken() after close = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/relex.go
+
+package lexer
+
+import (
+	"fmt"
+	"io"
+)
+
+// A change to a source, replacing the bytes [Start, End) with the Text.
+type TextEdit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Returns the source with the edit applied, leaving the original unmodified.
+func (edit TextEdit) Apply(source []byte) []byte {
+	edited := make([]byte, 0, len(source)-(edit.End-edit.Start)+len(edit.Text))
+	edited = append(edited, source[:edit.Start]...)
+	edited = append(edited, edit.Text...)
+	return append(edited, source[edit.End:]...)
+}
+
+// Updates the tokens of a source for an edit to it, returning the tokens of
+// the edited source as they would be scanned from the start.  Only the region
+// affected by the edit is scanned again; the tokens before it are kept and the
+// tokens after it are kept with their lines and spans shifted.
+//
+// The tokens must be those scanned (e.g. by ScanAll) from the source, before the
+// edit, with the same Options.  Spans are byte offsets (+1) as though scanned
+// without a File, the Options' File is not used.  The diagnostics of the region
+// that is scanned again are not returned, the caller may scan the source again
+// to get all diagnostics when it needs them.
+//
+// Scanning restarts after the last sentence token (outside any parentheses)
+// that is followed by a token on a later line, before the edit.  At such a
+// point the scanner is not within a string, comment or expression, so it can
+// resume from there with only the line number and the tokens' flags to restore.
+// Scanning stops at the first such point after the edit where the scanner's
+// state matches the one found at the same line start in the original tokens,
+// as everything that follows is then unchanged.
+func Relex(tokens []Token, source []byte, edit TextEdit, opts Options) ([]Token, error) {
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(source) {
+		return nil, fmt.Errorf("edit [%d, %d) is outside the source of %d bytes",
+			edit.Start, edit.End, len(source))
+	}
+	opts.File = nil
+	edited := edit.Apply(source)
+	delta := len(edit.Text) - (edit.End - edit.Start)
+
+	old := newRelexPoints(tokens, source, opts.Trivia)
+	restart := 0
+	for k := len(tokens) - 1; k > 0; k-- {
+		if old.isRestart(k) && old.fullEnd(k-1) < edit.Start {
+			restart = k
+			break
+		}
+	}
+
+	// Resume scanning at the end of the last token kept, at its line and column.
+	offset := 0
+	if restart > 0 {
+		offset = old.fullEnd(restart - 1)
+	}
+	lineStart := lastLineStart(edited[:offset])
+	in := newBytesInput(edited[lineStart:], opts)
+	in.base = lineStart
+	in.line = uint(countLineBreaks(edited[:lineStart])) + 1
+	in.consume(offset - lineStart)
+	reader := newLexer(in, opts)
+	if restart > 0 {
+		reader.inBody = true
+		reader.lastLine = tokens[restart-1].Line()
+	}
+
+	// Old tokens that begin a line after the edit, by the offset of their line
+	// start in the edited source, where scanning may resume the old tokens.
+	syncAt := make(map[int]int)
+	for m := restart + 1; m < len(tokens); m++ {
+		if !old.isRestart(m) {
+			continue
+		}
+		if start := old.lineStart(m); start >= edit.End {
+			syncAt[start+delta] = m
+		}
+	}
+
+	updated := append([]Token{}, tokens[:restart]...)
+	scanned := newRelexPoints(updated, edited, opts.Trivia)
+	for token, err := range reader.All() {
+		if err != nil && err != io.EOF {
+			return updated, err
+		}
+		updated = append(updated, token)
+		scanned.tokens = updated
+		j := len(updated) - 1
+		if j == 0 || token.TokenType == EOF.TokenType || !scanned.isRestart(j) {
+			continue
+		}
+		m, ok := syncAt[scanned.lineStart(j)]
+		if !ok {
+			continue
+		}
+		// The scanner is in the same state as it was at the same text in the old
+		// tokens, so the rest of the old tokens follow (including this one).
+		lines := int(token.Line()) - int(tokens[m].Line())
+		for _, token := range tokens[m+1:] {
+			updated = append(updated, token.shifted(lines, delta))
+		}
+		break
+	}
+	return updated, nil
+}
+
+// Moves the token by a number of lines and bytes, retaining its column.
+func (token Token) shifted(lines, bytes int) Token {
+	if token.Line() > 0 {
+		token.TokenPos = rawTokenPos(uint(int(token.Line())+lines),
+			token.Column(), token.flag())
+	}
+	if token.Span.Start.IsValid() {
+		token.Span.Start += Pos(bytes)
+		token.Span.End += Pos(bytes)
+	}
+	return token
+}
+
+// The tokens scanned from a source, with the depth of parentheses after each,
+// for finding where scanning can restart.  The depths are found as needed.
+type relexPoints struct {
+	tokens []Token
+	source []byte
+	trivia bool
+	depths []int
+}
+
+func newRelexPoints(tokens []Token, source []byte, trivia bool) *relexPoints {
+	return &relexPoints{tokens: tokens, source: source, trivia: trivia}
+}
+
+// Returns true if scanning can restart at (or resume from) the k'th token: it
+// begins a line after a sentence token, and no parentheses are open before it.
+// The token before k is then the last of a line, and in the body of the file.
+func (points *relexPoints) isRestart(k int) bool {
+	before := points.tokens[k-1]
+	return IsSentence(before.TokenPos) && points.depth(k-1) == 0 &&
+		points.tokens[k].Line() > before.Line()
+}
+
+// Returns the number of parentheses open after the i'th token.
+func (points *relexPoints) depth(i int) int {
+	for len(points.depths) <= i {
+		n, depth := len(points.depths), 0
+		if n > 0 {
+			depth = points.depths[n-1]
+		}
+		switch points.tokens[n].TokenType.(type) {
+		case *ExprStartToken:
+			depth += 1
+		case *ExprEndToken:
+			depth = max(0, depth-1)
+		}
+		points.depths = append(points.depths, depth)
+	}
+	return points.depths[i]
+}
+
+// Returns the offset after the token and its trailing trivia, if any.
+func (points *relexPoints) fullEnd(i int) int {
+	token := points.tokens[i]
+	end := int(token.Span.End) - 1
+	if points.trivia && token.Trivia != nil {
+		for _, piece := range token.Trivia.Trailing {
+			end += len(piece.Text)
+		}
+	}
+	return end
+}
+
+// Returns the offset of the start of the line that the token begins on.
+func (points *relexPoints) lineStart(i int) int {
+	start := int(points.tokens[i].Span.Start) - 1
+	return lastLineStart(points.source[:start])
+}
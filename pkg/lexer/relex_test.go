@@ -0,0 +1,123 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/relex_test.go
+
+package lexer
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Checks that the tokens from Relex() are the same as scanning the edited
+// source from the beginning.
+func checkRelex(t *testing.T, source string, edit TextEdit, opts Options) {
+	t.Helper()
+	tokens, err := ScanAll(strings.NewReader(source), opts)
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	edited := string(edit.Apply([]byte(source)))
+	want, _ := ScanAll(strings.NewReader(edited), opts)
+	got, err := Relex(tokens, []byte(source), edit, opts)
+	if err != nil {
+		t.Fatalf("Relex() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		for i := range min(len(got), len(want)) {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Fatalf("Relex(%+v) token %d = %v %v, want %v %v", edit,
+					i, got[i], got[i].Span, want[i], want[i].Span)
+			}
+		}
+		t.Fatalf("Relex(%+v) has %d tokens, want %d", edit, len(got), len(want))
+	}
+}
+
+func TestRelex(t *testing.T) {
+	kif := "(role x)\n(role o)\n\n(init (cell 1 1 b))\n; a comment\n(<= (legal ?p noop)\n    (role ?p))\n(succ 1 2)\n"
+	gel := "%% The roles.\nrole X, O\n\nlet board := (*\n   a block comment *) [3, 3]\nfoo(\"text\") :- bar\n"
+	tests := []struct {
+		name   string
+		source string
+		edit   TextEdit
+		opts   Options
+	}{
+		{"insert a token", kif, TextEdit{24, 24, "(mark) "}, Options{}},
+		{"rename", kif, TextEdit{11, 15, "player"}, Options{}},
+		{"extend an identifier", kif, TextEdit{7, 7, "yz"}, Options{}},
+		{"remove a line", kif, TextEdit{9, 18, ""}, Options{}},
+		{"open a paren", kif, TextEdit{9, 9, "("}, Options{}},
+		{"close a paren", kif, TextEdit{44, 45, ""}, Options{}},
+		{"comment out", kif, TextEdit{64, 64, ";"}, Options{}},
+		{"open a string", kif, TextEdit{19, 19, "\""}, Options{}},
+		{"at the start", kif, TextEdit{0, 0, "; header\n"}, Options{}},
+		{"at the end", kif, TextEdit{len(kif), len(kif), "(terminal)"}, Options{}},
+		{"whole source", kif, TextEdit{0, len(kif), "(role x)"}, Options{}},
+		{"open a block comment", gel, TextEdit{24, 24, "(* "}, Options{Dialect: GEL}},
+		{"close a block comment", gel, TextEdit{43, 62, ""}, Options{Dialect: GEL}},
+		{"undocument", gel, TextEdit{0, 14, ""}, Options{Dialect: GEL}},
+		{"document", gel, TextEdit{24, 24, "%% The board.\n"}, Options{Dialect: GEL}},
+		{"crlf", strings.ReplaceAll(kif, "\n", "\r\n"), TextEdit{26, 26, "\r(mark)"}, Options{}},
+		{"crlf with trivia", strings.ReplaceAll(kif, "\n", "\r\n"), TextEdit{10, 10, "\n"},
+			Options{Trivia: true}},
+		{"with trivia", kif, TextEdit{24, 24, " ; note\n(mark)"}, Options{Trivia: true}},
+		{"trailing comment", kif, TextEdit{8, 8, " ; x"}, Options{Trivia: true}},
+		{"gel with trivia", gel, TextEdit{62, 62, "\n\n"}, Options{Dialect: GEL, Trivia: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRelex(t, tt.source, tt.edit, tt.opts)
+		})
+	}
+}
+
+func TestRelex_InvalidEdit(t *testing.T) {
+	if _, err := Relex(nil, []byte("(role x)"), TextEdit{4, 20, ""}, Options{}); err == nil {
+		t.Errorf("Relex() of an edit beyond the source should fail")
+	}
+}
+
+// Relex() ought to agree with a full scan for any edit, such as those made by
+// cutting and pasting random parts of the example games.
+func TestRelex_Examples(t *testing.T) {
+	paths, _ := filepath.Glob("../../examples/*.ggd")
+	random := rand.New(rand.NewSource(1))
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(source) == 0 {
+			continue
+		}
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			for range 20 {
+				start := random.Intn(len(source))
+				end := min(len(source), start+random.Intn(40))
+				from := random.Intn(len(source))
+				text := source[from:min(len(source), from+random.Intn(40))]
+				edit := TextEdit{start, end, string(bytes.ToValidUTF8(text, nil))}
+				checkRelex(t, string(source), edit, Options{Dialect: GEL})
+				checkRelex(t, string(source), edit, Options{Dialect: GEL, Trivia: true})
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/sentences.go
+
+package lexer
+
+// Sets the token's flag (comment or sentence) and updates the nesting depth of
+// parentheses.  A ')' without a matching '(' is reported as a Diagnostic, but
+// is still produced as a CLOSE_PAREN token and does not change the depth.
+func (reader *lexerState) trackSentence(token Token) Token {
+	switch token.TokenType.(type) {
+	case *comment, *DocCommentToken:
+		token.TokenPos = token.InComment()
+		return token
+	case *MetaToken:
+		token.TokenPos = token.InMetaBlock()
+		return token
+	case *ExprStartToken:
+		reader.opened = append(reader.opened, token)
+	case *ExprEndToken:
+		if len(reader.opened) == 0 {
+			reader.errorAt(token.TokenPos, CODE_UNBALANCED_PAREN,
+				"unbalanced ')' without a matching '('")
+			reader.diagnostics[len(reader.diagnostics)-1].Span = token.Span
+		} else {
+			reader.opened = reader.opened[:len(reader.opened)-1]
+		}
+	}
+	reader.inBody = true
+	token.TokenPos = token.InSentence()
+	return token
+}
+
+// Reports a Diagnostic for each '(' that is still open at the end of input.
+func (reader *lexerState) closeSentences() {
+	for _, open := range reader.opened {
+		reader.errorAt(open.TokenPos, CODE_UNCLOSED_PAREN, "unclosed '(' at end of input")
+		reader.diagnostics[len(reader.diagnostics)-1].Span = open.Span
+	}
+	reader.opened = reader.opened[:0]
+}
+
+// Groups the tokens of a KIF source into its top-level sentences, omitting any
+// comments (and meta lines) between or within them.  Each sentence is either a
+// parenthesized expression or a lone token at the top level.  If the parentheses
+// are not balanced (see TokenScanner.Diagnostics()) the final sentence may be
+// incomplete.
+func Sentences(tokens []Token) [][]Token {
+	sentences := [][]Token{}
+	var sentence []Token
+	depth := 0
+	for _, token := range tokens {
+		if IsComment(token.TokenPos) || IsMetaBlock(token.TokenPos) {
+			continue
+		}
+		sentence = append(sentence, token)
+		switch token.TokenType.(type) {
+		case *ExprStartToken:
+			depth += 1
+		case *ExprEndToken:
+			if depth > 0 {
+				depth -= 1
+			}
+		}
+		if depth == 0 {
+			sentences = append(sentences, sentence)
+			sentence = nil
+		}
+	}
+	if len(sentence) > 0 {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/sentences_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_Flags(t *testing.T) {
+	input := "; header\n(role x) terminal ; trailing\n(init (cell 1))"
+	tokens, err := ScanAll(strings.NewReader(input), Options{})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	flags := make([]string, len(tokens))
+	for i, token := range tokens {
+		flags[i] = token.TokenPos.String()[:1]
+	}
+	want := "; . . . . . ; . . . . . . ."
+	if got := strings.Join(flags, " "); got != want {
+		t.Errorf("ScanAll() flags = %s, want %s", got, want)
+	}
+}
+
+func TestScan_Balance(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Diagnostic
+	}{
+		{"balanced", "(a (b)) (c)", nil},
+		{"extra close", "(a)) (b)", []Diagnostic{
+			{NewTokenPos(1, 4), Span{4, 5}, SEVERITY_ERROR, CODE_UNBALANCED_PAREN,
+				"unbalanced ')' without a matching '('"}}},
+		{"unclosed", "(a (b)\n(c", []Diagnostic{
+			{NewTokenPos(1, 1), Span{1, 2}, SEVERITY_ERROR, CODE_UNCLOSED_PAREN,
+				"unclosed '(' at end of input"},
+			{NewTokenPos(2, 1), Span{8, 9}, SEVERITY_ERROR, CODE_UNCLOSED_PAREN,
+				"unclosed '(' at end of input"}}},
+		{"comment parens", "; (\n(a) ; )", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{})
+			for range scanner.All() {
+			}
+			if !reflect.DeepEqual(scanner.Diagnostics(), tt.want) {
+				t.Errorf("Diagnostics() = %v, want %v", scanner.Diagnostics(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", []string{}},
+		{"one", "(role x)", []string{"(role x)"}},
+		{"several", "(role x)\n; comment\n(role o) (init (cell 1 1 b))",
+			[]string{"(role x)", "(role o)", "(init (cell 1 1 b))"}},
+		{"atoms", "terminal (role x) draw",
+			[]string{"terminal", "(role x)", "draw"}},
+		{"inner comment", "(<= terminal ; why\n  (line x))",
+			[]string{"(<= terminal (line x))"}},
+		{"unclosed", "(role x) (role", []string{"(role x)", "(role"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _ := ScanAll(strings.NewReader(tt.input), Options{})
+			got := []string{}
+			for _, sentence := range Sentences(tokens) {
+				images := make([]string, len(sentence))
+				for i, token := range sentence {
+					images[i] = token.Image()
+				}
+				text := strings.Join(images, " ")
+				text = strings.ReplaceAll(strings.ReplaceAll(text, "( ", "("), " )", ")")
+				got = append(got, text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sentences() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
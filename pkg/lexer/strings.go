@@ -0,0 +1,166 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/strings.go
+
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const RUNE_DOUBLE_QUOTE = '"'
+
+// Reads a double-quoted string from the pending '"' up to its closing quote.
+// Strings may not contain a newline; if one is found, or input ends before the
+// closing quote, the partial string is returned as an UnexpectedToken and a
+// Diagnostic is recorded.  The newline is left pending for the next token.
+func (reader *lexerState) readString() Token {
+	pos := reader.begin()
+	reader.next()
+
+	escaped := false
+	for {
+		r, size := reader.peek()
+		if size == 0 {
+			reader.errorAt(pos, CODE_UNTERMINATED_STRING, "unterminated string")
+			return UnexpectedToken(string(reader.image()), pos)
+		}
+		if isLineBreak(r) {
+			reader.errorAt(pos, CODE_NEWLINE_IN_STRING, "newline in string")
+			return UnexpectedToken(string(reader.image()), pos)
+		}
+		reader.advance(r, size)
+		if escaped {
+			escaped = false
+		} else if r == '\\' {
+			escaped = true
+		} else if r == RUNE_DOUBLE_QUOTE {
+			break
+		}
+	}
+
+	raw := string(reader.image())
+	value, offset, err := unquote(raw)
+	if err != nil {
+		reader.errorAt(pos.NextAt(0, uint(offset)), CODE_INVALID_ESCAPE, "%s", err)
+		return UnexpectedToken(raw, pos)
+	}
+	return Token{TokenPos: pos, TokenType: &StringToken{raw, value}}
+}
+
+// Decodes the escape sequences of a double-quoted string, returning the value
+// within the quotes.  The escapes are the same as JSON's: \" \\ \/ \b \f \n \r
+// \t and \uXXXX (with surrogate pairs combined).  If an escape is invalid, the
+// column offset (in runes) of its backslash is returned along with the error.
+func unquote(raw string) (string, int, error) {
+	runes := []rune(raw)
+	if len(runes) < 2 || runes[0] != RUNE_DOUBLE_QUOTE ||
+		runes[len(runes)-1] != RUNE_DOUBLE_QUOTE {
+		return "", 0, fmt.Errorf("string is not double-quoted")
+	}
+	runes = runes[1 : len(runes)-1]
+	var value strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' {
+			value.WriteRune(runes[i])
+			continue
+		}
+		start := i
+		i++
+		if i == len(runes) {
+			return "", start + 1, fmt.Errorf("unterminated escape in string")
+		}
+		switch runes[i] {
+		case '"', '\\', '/':
+			value.WriteRune(runes[i])
+		case 'b':
+			value.WriteRune('\b')
+		case 'f':
+			value.WriteRune('\f')
+		case 'n':
+			value.WriteRune('\n')
+		case 'r':
+			value.WriteRune('\r')
+		case 't':
+			value.WriteRune('\t')
+		case 'u':
+			r, ok := hex4(runes[i+1:])
+			if !ok {
+				return "", start + 1, fmt.Errorf("invalid unicode escape in string")
+			}
+			i += 4
+			if utf16.IsSurrogate(r) && i+6 < len(runes) &&
+				runes[i+1] == '\\' && runes[i+2] == 'u' {
+				if low, ok := hex4(runes[i+3:]); ok {
+					if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			value.WriteRune(r)
+		default:
+			return "", start + 1,
+				fmt.Errorf("invalid escape sequence '\\%c' in string", runes[i])
+		}
+	}
+	return value.String(), 0, nil
+}
+
+// Decodes the escape sequences of a double-quoted string, as for the Value() of
+// a STRING token, for other readers of the same string syntax.
+func Unquote(raw string) (string, error) {
+	value, _, err := unquote(raw)
+	return value, err
+}
+
+// Parses four hexadecimal digits from the start of runes, if there are four.
+func hex4(runes []rune) (rune, bool) {
+	if len(runes) < 4 {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(string(runes[:4]), 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(value), true
+}
+
+// A token representing a double-quoted string literal.  Its image is the raw
+// text (including quotes and escapes) so that it can be written back exactly
+// as it was read; the decoded contents of the string are given by Value().
+type StringToken struct {
+	raw   string
+	value string
+}
+
+// Constructs a STRING token from its raw image, decoding its escapes.  If the
+// escapes are invalid an UnexpectedToken is returned instead.
+func StringLiteral(raw string, pos TokenPos) Token {
+	value, _, err := unquote(raw)
+	if err != nil {
+		return UnexpectedToken(raw, pos)
+	}
+	return Token{TokenPos: pos, TokenType: &StringToken{raw, value}}
+}
+func (str *StringToken) TypeString() string { return "STRING" }
+func (str *StringToken) Image() string      { return str.raw }
+
+// Returns the contents of the string, with its escape sequences decoded.
+func (str *StringToken) Value() string { return str.value }
@@ -0,0 +1,124 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/strings_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadString(t *testing.T) {
+	startPos := NewTokenPos(1, 1)
+	tests := []struct {
+		name            string
+		input           string
+		want            Token
+		wantValue       string
+		wantRemaining   string
+		wantDiagnostics []Diagnostic
+	}{
+		{"empty", `""`, StringLiteral(`""`, startPos), "", "", nil},
+		{"simple", `"X" }`, StringLiteral(`"X"`, startPos), "X", " }", nil},
+		{"spaces", `"a b"`, StringLiteral(`"a b"`, startPos), "a b", "", nil},
+		{"escaped quote", `"say \"hi\""`, StringLiteral(`"say \"hi\""`, startPos),
+			`say "hi"`, "", nil},
+		{"escapes", `"\\\/\b\f\n\r\t"`, StringLiteral(`"\\\/\b\f\n\r\t"`, startPos),
+			"\\/\b\f\n\r\t", "", nil},
+		{"unicode escape", `"g\u00f8\u00F8d"`, StringLiteral(`"g\u00f8\u00F8d"`, startPos),
+			"gøød", "", nil},
+		{"surrogate pair", `"\ud83c\udfb2"`, StringLiteral(`"\ud83c\udfb2"`, startPos),
+			"🎲", "", nil},
+		{"unescaped unicode", `"gøød"`, StringLiteral(`"gøød"`, startPos), "gøød", "", nil},
+		{"unterminated", `"abc`, UnexpectedToken(`"abc`, startPos), "", "",
+			[]Diagnostic{{TokenPos: startPos, Code: CODE_UNTERMINATED_STRING,
+				Message: "unterminated string"}}},
+		{"unterminated escape", `"abc\"`, UnexpectedToken(`"abc\"`, startPos), "", "",
+			[]Diagnostic{{TokenPos: startPos, Code: CODE_UNTERMINATED_STRING,
+				Message: "unterminated string"}}},
+		{"newline", "\"abc\ndef\"", UnexpectedToken(`"abc`, startPos), "", "\ndef\"",
+			[]Diagnostic{{TokenPos: startPos, Code: CODE_NEWLINE_IN_STRING,
+				Message: "newline in string"}}},
+		{"invalid escape", `"ab\x"`, UnexpectedToken(`"ab\x"`, startPos), "", "",
+			[]Diagnostic{{TokenPos: NewTokenPos(1, 4), Code: CODE_INVALID_ESCAPE,
+				Message: `invalid escape sequence '\x' in string`}}},
+		{"short unicode escape", `"\u12"`, UnexpectedToken(`"\u12"`, startPos), "", "",
+			[]Diagnostic{{TokenPos: NewTokenPos(1, 2), Code: CODE_INVALID_ESCAPE,
+				Message: "invalid unicode escape in string"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newTestLexer(tt.input, startPos, KIF)
+			got := reader.readString()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lexerState.readString() = %v, want %v", got, tt.want)
+			}
+			if str, ok := got.TokenType.(*StringToken); ok && str.Value() != tt.wantValue {
+				t.Errorf("StringToken.Value() = %q, want %q", str.Value(), tt.wantValue)
+			}
+			if !reflect.DeepEqual(reader.Diagnostics(), tt.wantDiagnostics) {
+				t.Errorf("lexerState.Diagnostics() = %v, want %v", reader.Diagnostics(), tt.wantDiagnostics)
+			}
+			if rest := reader.remaining(); rest != tt.wantRemaining {
+				t.Errorf("lexerState.readString() left %q, want %q",
+					rest, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr string
+	}{
+		{`"a\tb"`, "a\tb", ""},
+		{`"\uD83D\uDE00"`, "\U0001F600", ""},
+		{`"\q"`, "", `invalid escape sequence '\q' in string`},
+		{`"\u12"`, "", "invalid unicode escape in string"},
+		{`x`, "", "string is not double-quoted"},
+	}
+	for _, tt := range tests {
+		got, err := Unquote(tt.raw)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Unquote(%s) error = %v, want %s", tt.raw, err, tt.wantErr)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("Unquote(%s) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestScan_Strings(t *testing.T) {
+	input := "x $= 100 :- board.Line(\"X\")\nBLANK <=> \"\"\n\"open"
+	tokens, err := ScanAll(strings.NewReader(input), Options{Dialect: GEL})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	images := make([]string, len(tokens))
+	for i, token := range tokens {
+		images[i] = token.TypeString() + ":" + token.Image()
+	}
+	want := `IDENT:x DOLLAR_EQ:$= INTEGER:100 COLON_DASH::- IDENT:board DOT:. ` +
+		`IDENT:Line OPEN_PAREN:( STRING:"X" CLOSE_PAREN:) ` +
+		`IDENT:BLANK ARROW_LRD:<=> STRING:"" UNEXPECTED:"open`
+	if got := strings.Join(images, " "); got != want {
+		t.Errorf("ScanAll()\n  got %s\n want %s", got, want)
+	}
+}
@@ -17,6 +17,10 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -24,94 +28,151 @@ import (
 type SymbolToken struct{}
 
 const (
-	RUNE_OPEN_PAREN    = '('
-	RUNE_CLOSE_PAREN   = ')'
-	RUNE_QUESTION_MARK = '?'
-	RUNE_COMMENT_SEMI  = ';'
+	RUNE_OPEN_PAREN   = '('
+	RUNE_CLOSE_PAREN  = ')'
+	RUNE_COMMENT_SEMI = ';'
 
 	RUNE_BEGIN_ARROW_LD = '<'
 	IMAGE_ARROW_LD      = "<="
 )
 
 // Using the pending rune and (optionally) additional runes from input,
-// reads a Symbol token.  This method only reads when an additional rune
-// is expected, so any EOF errors propagated will not include a valid
-// Token (but may include an IllegalToken token).
+// reads a Symbol token.  The longest operator matching the input is produced,
+// and any runes read beyond it are left in the input for the next token.
+// If no operator matches, the longest prefix of an operator (or else the one
+// rune read) is returned as an UnexpectedToken and reported as an unknown
+// operator, leaving the rune after it to begin the next token.
 func (reader *lexerState) readOperator() Token {
-	var r rune
-	reader.cursor, r = reader.cursor.FirstRune(reader.input)
-	var token Token
-
-	switch r {
-	case RUNE_OPEN_PAREN:
-		cursor := reader.cursor
-		reader.cursor, _ = cursor.ConsumeAll()
-		token = ExpressionStart(cursor.Pos())
-
-	case RUNE_CLOSE_PAREN:
-		cursor := reader.cursor
-		reader.cursor, _ = cursor.ConsumeAll()
-		token = ExpressionEnd(cursor.Pos())
-
-	case RUNE_QUESTION_MARK:
-		cursor := reader.cursor
-		reader.cursor, _ = cursor.ConsumeAll()
-		token = QuestionMark(cursor.Pos())
-
-	case RUNE_BEGIN_ARROW_LD: // looking for two runes, <=
-		reader.cursor, r = reader.cursor.NextRune(reader.input)
-		cursor := reader.cursor
-		var image string
-		if reader.cursor.HasError() {
-			// In GDL the '<' symbol by itself means nothing,
-			// return it as an IllegalToken if we see any error, including EOF.
-			reader.cursor, image = cursor.ConsumeAll()
-			token = UnexpectedToken(image, cursor.Pos())
-		} else {
-			if r == rune(IMAGE_ARROW_LD[1]) {
-				reader.cursor, _ = cursor.ConsumeAll()
-				token = LeftDoubleArrow(cursor.Pos())
-			} else {
-				if unicode.IsSpace(r) {
-					reader.cursor, image = cursor.ConsumeExceptFinal()
-				} else {
-					reader.cursor, image = cursor.ConsumeAll()
-				}
-				token = UnexpectedToken(image, cursor.Pos())
-			}
-		}
+	operators, prefixes := reader.dialect.operators, reader.dialect.prefixes
+	pos := reader.begin()
 
-	case RUNE_COMMENT_SEMI:
-		cursor := reader.cursor
-		for !reader.cursor.HasError() && r != '\n' {
-			reader.cursor, r = reader.cursor.NextRune(reader.input)
+	// Operator images are looked up by the bytes following the read position,
+	// without consuming them until the longest match is known.
+	var matched TokenType
+	var matchedLen int
+	length, prefixLen := 0, 0
+	for {
+		_, size := reader.peekAt(length)
+		if size == 0 {
+			break
+		}
+		length += size
+		image := reader.buf[reader.pos : reader.pos+length]
+		if optype, ok := operators[string(image)]; ok {
+			matched, matchedLen = optype, length
 		}
-		var image string
-		if reader.cursor.HasError() {
-			reader.cursor, image = reader.cursor.ConsumeAll()
-		} else {
-			// No errors, exclude the last pending character, it is a '\n' newline.
-			reader.cursor, image = reader.cursor.ConsumeExceptFinal()
-			reader.cursor, _ = reader.cursor.ConsumeAll()
+		if !prefixes[string(image)] {
+			break
 		}
-		token = LineComment(image, cursor.Pos())
+		prefixLen = length
+	}
 
-	default:
-		cursor := reader.cursor
-		var image string
-		reader.cursor, image = cursor.ConsumeAll()
-		token = UnexpectedToken(string(image), cursor.Pos())
+	if matched != nil {
+		reader.consume(matchedLen)
+	}
+	if marker, ok := matched.(commentMarker); ok {
+		return reader.readLineComment(marker.image, pos)
+	}
+	if marker, ok := matched.(blockCommentMarker); ok {
+		return reader.readBlockComment(marker, pos)
+	}
+	if matched != nil {
+		return Token{TokenPos: pos, TokenType: matched}
 	}
+	// In GEL a partial operator such as '<' by itself means nothing, so return
+	// it as an UnexpectedToken.  The rune that ended it is not consumed, as it may
+	// begin the next token (e.g. the '(' in `<(` or the quote in `<"`).
+	if prefixLen == 0 {
+		_, prefixLen = reader.peek()
+	}
+	reader.consume(prefixLen)
+	image := string(reader.image())
+	reader.errorAt(pos, CODE_UNKNOWN_OPERATOR, "unknown operator '%s'", image)
+	return UnexpectedToken(image, pos)
+}
+
+// Reads from the comment marker (e.g. ';' in KIF, already consumed) until the
+// end of the line, producing a LineComment.  Within the header of a file, before
+// the first sentence, a comment beginning with the marker and a '!' is a meta
+// line.  The line break is left pending as the space before the next token.
+func (reader *lexerState) readLineComment(marker string, pos TokenPos) Token {
+	reader.skipWhile(func(r rune) bool { return !isLineBreak(r) })
+	image := string(reader.image())
+	if !reader.inBody && strings.HasPrefix(image, marker+META_MARKER) {
+		return reader.readMeta(image, marker, pos)
+	}
+	return LineComment(image, pos)
+}
 
-	return token
+// Most operators are only distinguished by their type name and image, so they
+// share this TokenType representation instead of each having a distinct type.
+type OperatorToken struct {
+	name  string
+	image string
 }
 
+// Satisfies the requirement for TokenType interface.
+func (tok OperatorToken) TypeString() string { return tok.name }
+
+// Satisfies the requirement for TokenType interface.
+func (tok OperatorToken) Image() string { return tok.image }
+
+// Constructs a Token instance for this operator at the indicated position.
+func (tok OperatorToken) At(pos TokenPos) Token {
+	return Token{TokenPos: pos, TokenType: tok}
+}
+
+var (
+	// Relations and definitions.
+	COLON_DASH = OperatorToken{"COLON_DASH", ":-"}
+	ARROW_RD   = OperatorToken{"ARROW_RD", "==>"}
+	ARROW_R    = OperatorToken{"ARROW_R", "->"}
+	ARROW_L    = OperatorToken{"ARROW_L", "<-"}
+	ARROW_LRD  = OperatorToken{"ARROW_LRD", "<=>"}
+	DOLLAR_EQ  = OperatorToken{"DOLLAR_EQ", "$="}
+	COLON_EQ   = OperatorToken{"COLON_EQ", ":="}
+	EQUALS     = OperatorToken{"EQUALS", "="}
+	COLON      = OperatorToken{"COLON", ":"}
+
+	// Ordering and comparison.
+	LT_LT      = OperatorToken{"LT_LT", "<<"}
+	GT_GT      = OperatorToken{"GT_GT", ">>"}
+	LT_LT_LT   = OperatorToken{"LT_LT_LT", "<<<"}
+	TRIPLE_EQ  = OperatorToken{"TRIPLE_EQ", "==="}
+	TRIPLE_NE  = OperatorToken{"TRIPLE_NE", "=/="}
+	LT_HASH_GT = OperatorToken{"LT_HASH_GT", "<#>"}
+
+	// Access, ranges and separators.
+	AT_SIGN   = OperatorToken{"AT_SIGN", "@"}
+	DOT_DOT   = OperatorToken{"DOT_DOT", ".."}
+	DOT       = OperatorToken{"DOT", "."}
+	COMMA     = OperatorToken{"COMMA", ","}
+	PIPE      = OperatorToken{"PIPE", "|"}
+	BACKSLASH = OperatorToken{"BACKSLASH", "\\"}
+
+	// Conjunction, negation and distinction in HRF.
+	AMPERSAND   = OperatorToken{"AMPERSAND", "&"}
+	TILDE       = OperatorToken{"TILDE", "~"}
+	HASH        = OperatorToken{"HASH", "#"}
+	COLON_COLON = OperatorToken{"COLON_COLON", "::"}
+
+	// Brackets, braces and their compositions.
+	OPEN_BRACKET        = OperatorToken{"OPEN_BRACKET", "["}
+	CLOSE_BRACKET       = OperatorToken{"CLOSE_BRACKET", "]"}
+	OPEN_BRACE          = OperatorToken{"OPEN_BRACE", "{"}
+	CLOSE_BRACE         = OperatorToken{"CLOSE_BRACE", "}"}
+	OPEN_DBRACE         = OperatorToken{"OPEN_DBRACE", "{{"}
+	CLOSE_DBRACE        = OperatorToken{"CLOSE_DBRACE", "}}"}
+	OPEN_BRACE_BRACKET  = OperatorToken{"OPEN_BRACE_BRACKET", "{["}
+	CLOSE_BRACKET_BRACE = OperatorToken{"CLOSE_BRACKET_BRACE", "]}"}
+)
+
 // Token EXPR_START = "("
 type ExprStartToken struct{ SymbolToken }
 
 // Begins all expressions, the main structural denotation in GDL syntax.
 func ExpressionStart(pos TokenPos) Token {
-	return Token{pos, &EXPR_START}
+	return Token{TokenPos: pos, TokenType: &EXPR_START}
 }
 func (tok ExprStartToken) TypeString() string { return "OPEN_PAREN" }
 func (tok ExprStartToken) Image() string      { return string(RUNE_OPEN_PAREN) }
@@ -123,7 +184,7 @@ type ExprEndToken struct{ SymbolToken }
 
 // Indicates the end of expressions and sub-expressions within a sentence.
 func ExpressionEnd(pos TokenPos) Token {
-	return Token{pos, &EXPR_END}
+	return Token{TokenPos: pos, TokenType: &EXPR_END}
 }
 func (tok ExprEndToken) TypeString() string { return "CLOSE_PAREN" }
 func (tok ExprEndToken) Image() string      { return string(RUNE_CLOSE_PAREN) }
@@ -135,61 +196,105 @@ type LDArrowToken struct{ SymbolToken }
 
 // Used in constructing relations.
 func LeftDoubleArrow(pos TokenPos) Token {
-	return Token{pos, &ARROW_LD}
+	return Token{TokenPos: pos, TokenType: &ARROW_LD}
 }
 func (tok LDArrowToken) TypeString() string { return "ARROW_LD" }
 func (tok LDArrowToken) Image() string      { return IMAGE_ARROW_LD }
 
 var ARROW_LD LDArrowToken
 
-// Token QUE_MARK = "?"
-type QMarkToken struct{ SymbolToken }
-
-// Used in the production rule for Variable terms.
-func QuestionMark(pos TokenPos) Token {
-	return Token{pos, &QUE_MARK}
-}
-func (tok QMarkToken) TypeString() string { return "QUE_MARK" }
-func (tok QMarkToken) Image() string      { return string(RUNE_QUESTION_MARK) }
-
-var QUE_MARK QMarkToken
-
-// Read the pending runes and into reader.input as needed, to read an Integer.
+// Reads an Integer from the pending runes.
+// The integer may be negative, with a '-' immediately before its first digit.
+// Only ASCII digits are accepted in rules; other decimal digits are read as
+// part of the number (and normalised in its value) but reported as an error.
+// Reading stops at the first non-digit, so a range `1..3` is INTEGER DOT_DOT
+// INTEGER rather than a malformed number.
 func (reader *lexerState) readNumber() Token {
-	var r rune
-	reader.cursor, r = reader.cursor.FirstRune(reader.input)
+	pos := reader.begin()
+	if r, _ := reader.peek(); r == RUNE_MINUS {
+		reader.next()
+	}
 
 	// This method should only be called if the first digit rune has already been
 	// peeked at or if the grammar would require the next token to be an Integer,
 	// so return an UnexpectedToken if that is not the case.
-	if !unicode.IsDigit(r) {
-		return reader.consumeUnexpectedToken()
+	if r, size := reader.peek(); size == 0 || !unicode.IsDigit(r) {
+		return reader.consumeUnexpectedToken(pos)
 	}
 
-	for !reader.cursor.HasError() && unicode.IsDigit(r) {
-		reader.cursor, r = reader.cursor.NextRune(reader.input)
+	nonASCII := false
+	for {
+		r, size := reader.peek()
+		if size == 0 || !unicode.IsDigit(r) {
+			break
+		}
+		if r > unicode.MaxASCII && !nonASCII {
+			reader.errorAt(pos, CODE_NON_ASCII_DIGIT, "non-ASCII digit %q in number", r)
+			nonASCII = true
+		}
+		reader.advance(r, size)
 	}
-	cursor := reader.cursor
-	var image string
-	if cursor.HasError() {
-		// Produce the Integer token and maintain the error status in the cursor.
-		reader.cursor, image = cursor.ConsumeAll()
-		return Integer(image, cursor.Pos())
+	integer := reader.integer(reader.image())
+	if _, err := integer.Value(); err != nil {
+		reader.errorAt(pos, CODE_INTEGER_RANGE, "%s", err)
 	}
+	return Token{TokenPos: pos, TokenType: integer}
+}
 
-	reader.cursor, image = cursor.ConsumeExceptFinal()
-	return Integer(image, cursor.Pos())
+// Returns true if the rune after the pending '-' is a digit.
+func (reader *lexerState) peekDigit() bool {
+	r, size := reader.peekAt(1)
+	return size > 0 && unicode.IsDigit(r)
 }
 
-// A token representing a sequence of digits (an integer numeral).
-type integerToken struct{ image string }
+const RUNE_MINUS = '-'
+
+// A token representing an integer numeral, an optional '-' and its digits.
+type IntegerToken struct {
+	image string
+	value int64
+	err   error
+}
 
-// More complex numeric types can be constructed from sequences of unsigned
-// integers and punctuation.  This also keeps the tokenizer state management
-// simpler by defining negatives, floats, etc. in terms of production rule
-// semantics.  GDL and GDL-II both only assume integer constants in [0-100].
+// Negative integers are lexed as a single token, but other numeric types (e.g.
+// ranges) are constructed from integers and punctuation in production rules.
+// GDL and GDL-II both only assume integer constants in [0-100], while GEL uses
+// integers for goal values (`$= 50`) and ranges (`1..3`) as well.
 func Integer(image string, pos TokenPos) Token {
-	return Token{pos, &integerToken{image}}
+	value, err := parseInteger(image)
+	return Token{TokenPos: pos, TokenType: &IntegerToken{image, value, err}}
+}
+func (num *IntegerToken) TypeString() string { return "INTEGER" }
+func (num *IntegerToken) Image() string      { return num.image }
+
+// Returns the value of the integer, or an error if it does not fit in an int64.
+func (num *IntegerToken) Value() (int64, error) { return num.value, num.err }
+
+// Parses the image of an integer, normalising any non-ASCII decimal digits.
+func parseInteger(image string) (int64, error) {
+	digits := []rune(image)
+	for i, r := range digits {
+		if r > unicode.MaxASCII && unicode.IsDigit(r) {
+			digits[i] = '0' + digitValue(r)
+		}
+	}
+	value, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("integer %s is out of range", image)
+		}
+		return 0, fmt.Errorf("invalid integer %q", image)
+	}
+	return value, nil
+}
+
+// Returns the value of a decimal digit.  Unicode assigns decimal digits in
+// contiguous runs of ten, from zero to nine, so the value is found from the
+// distance to the start of the run of digits the rune belongs to.
+func digitValue(r rune) rune {
+	zero := r
+	for unicode.IsDigit(zero - 1) {
+		zero -= 1
+	}
+	return (r - zero) % 10
 }
-func (num *integerToken) TypeString() string { return "INTEGER" }
-func (num *integerToken) Image() string      { return num.image }
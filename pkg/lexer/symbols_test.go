@@ -17,122 +17,188 @@
 package lexer
 
 import (
-	"fmt"
-	"io"
 	"reflect"
 	"strings"
 	"testing"
 )
 
 func TestReadSymbol(t *testing.T) {
-	var startTokenPos = NewTokenPos(3, 2).InSentence()
+	var startTokenPos = NewTokenPos(3, 2)
 	tests := []struct {
-		name        string
-		pending     rune
-		input       string
-		want        Token
-		wantPending string
-		withEOF     bool
+		name          string
+		input         string
+		want          Token
+		wantRemaining string
 	}{
-		{"open expression", '(', "(", ExpressionStart(startTokenPos), "", false},
-		{"open expression then EOF", '(', "", ExpressionStart(startTokenPos), "", false},
-		{"close expression", ')', "))", ExpressionEnd(startTokenPos), "", false},
-		{"close expression then EOF", ')', "", ExpressionEnd(startTokenPos), "", false},
-		{"question mark", '?', "", QuestionMark(startTokenPos), "", false},
-		{"left double arrow", '<', "= ", LeftDoubleArrow(startTokenPos), "", false},
-		{"left double arrow then EOF", '<', "=", LeftDoubleArrow(startTokenPos), "", false},
-		{"left double arrow, partial", '<', "?", UnexpectedToken("<?", startTokenPos), "", false},
-		{"left double arrow, partial with EOF", '<', "", UnexpectedToken("<", startTokenPos), "", true},
-		{"left double arrow separated by space", '<', " =", UnexpectedToken("<", startTokenPos), " ", false},
-		{"unexpected exclamation mark", '!', "", UnexpectedToken("!", startTokenPos), "", false},
+		{"open expression", "((", ExpressionStart(startTokenPos), "("},
+		{"open expression then EOF", "(", ExpressionStart(startTokenPos), ""},
+		{"close expression", ")))", ExpressionEnd(startTokenPos), "))"},
+		{"close expression then EOF", ")", ExpressionEnd(startTokenPos), ""},
+		{"left double arrow", "<= ", LeftDoubleArrow(startTokenPos), " "},
+		{"left double arrow then EOF", "<=", LeftDoubleArrow(startTokenPos), ""},
+		{"left double arrow, partial", "<?", UnexpectedToken("<", startTokenPos), "?"},
+		{"left double arrow, partial with EOF", "<", UnexpectedToken("<", startTokenPos), ""},
+		{"left double arrow separated by space", "< =", UnexpectedToken("<", startTokenPos), " ="},
+		{"unexpected exclamation mark", "!", UnexpectedToken("!", startTokenPos), ""},
+		{"equivalence", "<=>", ARROW_LRD.At(startTokenPos), ""},
+		{"shift left", "<<x", LT_LT.At(startTokenPos), "x"},
+		{"triple shift", "<<<", LT_LT_LT.At(startTokenPos), ""},
+		{"consequence", "==> ", ARROW_RD.At(startTokenPos), " "},
+		{"identity", "=== ", TRIPLE_EQ.At(startTokenPos), " "},
+		{"not identical", "=/=", TRIPLE_NE.At(startTokenPos), ""},
+		{"equals, partial identity", "==x", EQUALS.At(startTokenPos), "=x"},
+		{"equals, partial difference", "=/x", EQUALS.At(startTokenPos), "/x"},
+		{"inference", ":- ", COLON_DASH.At(startTokenPos), " "},
+		{"assignment", ":= ", COLON_EQ.At(startTokenPos), " "},
+		{"colon", ": ", COLON.At(startTokenPos), " "},
+		{"goal value", "$= ", DOLLAR_EQ.At(startTokenPos), " "},
+		{"lone dollar", "$ ", UnexpectedToken("$", startTokenPos), " "},
+		{"hash operator", "<#>", LT_HASH_GT.At(startTokenPos), ""},
+		{"unknown operator", "<#x", UnexpectedToken("<#", startTokenPos), "x"},
+		{"unknown operator before paren", "<(a)", UnexpectedToken("<", startTokenPos), "(a)"},
+		{"unknown operator before string", "<\"x\"", UnexpectedToken("<", startTokenPos), "\"x\""},
+		{"range", "..3", DOT_DOT.At(startTokenPos), "3"},
+		{"property access", ".Line", DOT.At(startTokenPos), "Line"},
+		{"comprehension", "{[ ", OPEN_BRACE_BRACKET.At(startTokenPos), " "},
+		{"double brace", "{{ ", OPEN_DBRACE.At(startTokenPos), " "},
+		{"close brace", "} ", CLOSE_BRACE.At(startTokenPos), " "},
+		{"close bracket brace", "]}", CLOSE_BRACKET_BRACE.At(startTokenPos), ""},
+		{"open bracket", "[_", OPEN_BRACKET.At(startTokenPos), "_"},
+		{"binding", "\\row", BACKSLASH.At(startTokenPos), "row"},
+		{"arrow left", "<- ", ARROW_L.At(startTokenPos), " "},
+		{"arrow right", "->", ARROW_R.At(startTokenPos), ""},
 	}
 
 	for _, tt := range tests {
-		// Creating the lexer directly to more easily set and read the internals.
-		// See `plugg/tests` package for examples of using only the exported types.
-		reader := lexerState{
-			strings.NewReader(tt.input),
-			make(chan Token),
-			cursorState{
-				startTokenPos,
-				[]rune{ tt.pending },
-				nil }}
-
-		// Test the readUntilSymbol() procedure.
 		t.Run(tt.name, func(t *testing.T) {
+			// Creating the lexer directly to more easily read the internals.
+			// See `plugg/tests` package for examples of using only the exported types.
+			reader := newTestLexer(tt.input, startTokenPos, GEL)
 			tok := reader.readOperator()
-
-			if (reader.cursor.HasError() && reader.cursor.IsEOF()) != tt.withEOF {
-				t.Errorf("ConsumeSymbol() error = %v", reader.cursor.ErrorValue())
-			}
-			if (!reader.cursor.HasError() && tt.withEOF) {
-				t.Errorf("ConsumeSymbol() should have EOF but has no error")
-			}
 			if !reflect.DeepEqual(tok, tt.want) {
-				t.Errorf("ConsumeSymbol() returns %v, wanted %v", tok, tt.want)
+				t.Errorf("readOperator() returns %v, wanted %v", tok, tt.want)
 			}
-			var image string
-			reader.cursor, image = reader.cursor.ConsumeAll()
-			if !reflect.DeepEqual(image, string(tt.wantPending)) {
-				t.Errorf("readSymbol() cursor image = %v, want %v",
-					image, string(tt.wantPending))
+			if rest := reader.remaining(); rest != tt.wantRemaining {
+				t.Errorf("readOperator() left %q, want %q", rest, tt.wantRemaining)
 			}
 		})
 	}
 }
 
 func TestReadNumber(t *testing.T) {
-	var startTokenPos = NewTokenPos(1, 1).InSentence()
+	var startTokenPos = NewTokenPos(1, 1)
 	tests := []struct {
-		name        string
-		pending     rune
-		input       string
-		want        Token
-		wantPending []rune
-		wantEOF     bool
+		name          string
+		input         string
+		want          Token
+		wantRemaining string
 	}{
-		{"number then space", '1', "23 ",
-			Integer("123", startTokenPos), []rune{' '}, false},
-		{"number then EOF", '1', "23",
-			Integer("123", startTokenPos), []rune{}, true},
-		{"number then alpha", '1', "23a",
-			Integer("123", startTokenPos), []rune{'a'}, false},
-		{"single digit number", '1', "bc",
-			Integer("1", startTokenPos), []rune{'b'}, false},
-		{"nondigit pending value", 'a', "1c",
-			UnexpectedToken("a", startTokenPos), []rune{}, false},
+		{"number then space", "123 ", Integer("123", startTokenPos), " "},
+		{"number then EOF", "123", Integer("123", startTokenPos), ""},
+		{"number then alpha", "123a", Integer("123", startTokenPos), "a"},
+		{"single digit number", "1bc", Integer("1", startTokenPos), "bc"},
+		{"nondigit pending value", "a1c", UnexpectedToken("a", startTokenPos), "1c"},
+		{"negative number", "-42)", Integer("-42", startTokenPos), ")"},
+		{"range", "1..3", Integer("1", startTokenPos), "..3"},
+		{"minus without digits", "-x", UnexpectedToken("-x", startTokenPos), ""},
 	}
 	for _, tt := range tests {
-		reader := lexerState{
-			strings.NewReader(tt.input),
-			make(chan Token),
-			cursorState{
-				startTokenPos,
-				[]rune{tt.pending},
-				nil,
-			},
-		}
-
 		t.Run(tt.name, func(t *testing.T) {
+			reader := newTestLexer(tt.input, startTokenPos, KIF)
 			tok := reader.readNumber()
-			err := reader.cursor.ErrorValue()
-			if (err != nil) && err != io.EOF {
-				t.Error("readNumber() error and not io.EOF")
-				return
-			}
-			if err == io.EOF && !tt.wantEOF {
-				t.Errorf("readNumber() error %s not io.EOF", err)
-				return
-			}
-			fmt.Println(tt.want)
 			if !reflect.DeepEqual(tok, tt.want) {
 				t.Errorf("readNumber() = %v, want %v", tok, tt.want)
 			}
-			var image string
-			reader.cursor, image = reader.cursor.ConsumeAll()
-			if !reflect.DeepEqual(image, string(tt.wantPending)) {
-				t.Errorf("readNumber() cursor image = %v, want %v",
-					image, string(tt.wantPending))
+			if rest := reader.remaining(); rest != tt.wantRemaining {
+				t.Errorf("readNumber() left %q, want %q", rest, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestScan_Numbers(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      string
+		wantValue []int64
+		wantCodes []string
+	}{
+		{"left $= 50", "IDENT DOLLAR_EQ INTEGER", []int64{50}, nil},
+		{"\\row <- 1..3", "VARIABLE ARROW_L INTEGER DOT_DOT INTEGER",
+			[]int64{1, 3}, nil},
+		{"?x -> -5", "VARIABLE ARROW_R INTEGER", []int64{-5}, nil},
+		{"x-1", "IDENT INTEGER", []int64{-1}, nil},
+		{"- 1", "UNEXPECTED INTEGER", []int64{1}, []string{CODE_UNKNOWN_OPERATOR}},
+		{"\u0663\u0664", "INTEGER", []int64{34}, []string{CODE_NON_ASCII_DIGIT}},
+		{"-9223372036854775808", "INTEGER", []int64{-9223372036854775808}, nil},
+		{"9223372036854775808", "INTEGER", []int64{0}, []string{CODE_INTEGER_RANGE}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			scanner := NewScanner(strings.NewReader(tt.input), Options{Dialect: GEL})
+			types, values := []string{}, []int64{}
+			for token, err := range scanner.All() {
+				if err != nil {
+					t.Fatalf("All() yielded error %v", err)
+				}
+				types = append(types, token.TypeString())
+				if num, ok := token.TokenType.(*IntegerToken); ok {
+					value, _ := num.Value()
+					values = append(values, value)
+				}
+			}
+			if got := strings.Join(types, " "); got != tt.want {
+				t.Errorf("All() types\n  got %s\n want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(values, tt.wantValue) {
+				t.Errorf("IntegerToken.Value() = %v, want %v", values, tt.wantValue)
+			}
+			var codes []string
+			for _, diag := range scanner.Diagnostics() {
+				codes = append(codes, diag.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("Diagnostics() codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestScan_Operators(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"role(?p) @ board[_, _] -> Mark(?p.marker)",
+			"KEYWORD OPEN_PAREN VARIABLE CLOSE_PAREN AT_SIGN IDENT " +
+				"OPEN_BRACKET IDENT COMMA IDENT CLOSE_BRACKET ARROW_R IDENT OPEN_PAREN " +
+				"VARIABLE DOT IDENT CLOSE_PAREN"},
+		{"x $= 100, o $= 0 :- board.Line(_)",
+			"IDENT DOLLAR_EQ INTEGER COMMA IDENT DOLLAR_EQ INTEGER COLON_DASH " +
+				"IDENT DOT IDENT OPEN_PAREN IDENT CLOSE_PAREN"},
+		{"data Hand := ROCK << PAPER<<<SCISSORS >> ROCK",
+			"KEYWORD IDENT COLON_EQ IDENT LT_LT IDENT LT_LT_LT IDENT GT_GT IDENT"},
+		{"{[ BLANK | \\row <- 1..3 ]}",
+			"OPEN_BRACE_BRACKET IDENT PIPE VARIABLE ARROW_L INTEGER DOT_DOT " +
+				"INTEGER CLOSE_BRACKET_BRACE"},
+		{"{{ @[1, 1] }} === {{?m}}=/=x==>y<=>z<#>w",
+			"OPEN_DBRACE AT_SIGN OPEN_BRACKET INTEGER COMMA INTEGER CLOSE_BRACKET " +
+				"CLOSE_DBRACE TRIPLE_EQ OPEN_DBRACE VARIABLE CLOSE_DBRACE " +
+				"TRIPLE_NE IDENT ARROW_RD IDENT ARROW_LRD IDENT LT_HASH_GT IDENT"},
+		{"==x=/y", "EQUALS EQUALS IDENT EQUALS UNEXPECTED IDENT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(tt.input), Options{Dialect: GEL})
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			types := make([]string, len(tokens))
+			for i, token := range tokens {
+				types[i] = token.TypeString()
+			}
+			if got := strings.Join(types, " "); got != tt.want {
+				t.Errorf("ScanAll() types\n  got %s\n want %s", got, tt.want)
 			}
 		})
 	}
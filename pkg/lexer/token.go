@@ -29,23 +29,33 @@ type TokenType interface {
 // with state/context or reuse a shared instance for the many tokens that are
 // universally identical within their type (e.g. keywords, operator symbols).
 // TokenPos is a 32-bit uint composite value defined in [token_pos.go].
+//
+// The Span is the range of bytes the token was scanned from, as positions in a
+// FileSet (see [files.go]).  It is set by the scanner and is zero otherwise.
+// Trivia is only set when scanning with Options.Trivia, see [trivia.go].
 type Token struct {
 	TokenPos
 	TokenType
+	Span   Span
+	Trivia *Trivia
 }
 
 // Identifier is a catch-all token for alpha-num strings that are not keywords.
 func Identifier(name string, pos TokenPos) Token {
-	return Token{pos, &identToken{name}}
+	return Token{TokenPos: pos, TokenType: &identToken{name: name}}
 }
 func (ident *identToken) TypeString() string { return "IDENT" }
 func (ident *identToken) Image() string      { return ident.name }
+func (ident *identToken) Symbol() SymbolID   { return ident.symbol }
 
-type identToken struct{ name string }
+type identToken struct {
+	name   string
+	symbol SymbolID
+}
 
 // EOF token indicates the end of the token stream.
 // As EOF is not in the document, its TokenPos is always zero.
-var EOF = Token{kTOKENPOS_ZERO, &eofToken{}}
+var EOF = Token{TokenPos: kTOKENPOS_ZERO, TokenType: &eofToken{}}
 
 type eofToken struct{}
 
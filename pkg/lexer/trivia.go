@@ -0,0 +1,182 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/trivia.go
+
+package lexer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Trivia is the text between tokens that does not change the meaning of the
+// rules: spaces, newlines and comments.  When scanning with Options.Trivia, the
+// trivia is attached to the tokens so that formatters and refactoring tools can
+// reproduce the input exactly, and keep comments with the code they describe.
+//
+// A token's trailing trivia is the spaces after it and a comment on the same
+// line, up to (but not including) the next newline.  All other trivia leads the
+// token after it, e.g. in the following the comment trails the ')' while the
+// newline and indentation lead 'next':
+//
+//	(role x)  ; the first player
+//	  next
+type Trivia struct {
+	Leading  []TriviaPiece
+	Trailing []TriviaPiece
+}
+
+// A single space, newline or comment, see Trivia.
+type TriviaPiece struct {
+	Kind TriviaKind
+	Text string
+}
+
+type TriviaKind uint8
+
+const (
+	TRIVIA_SPACE TriviaKind = iota
+	TRIVIA_NEWLINE
+	TRIVIA_COMMENT
+	TRIVIA_DOC_COMMENT
+	TRIVIA_BOM
+)
+
+func (kind TriviaKind) String() string {
+	switch kind {
+	case TRIVIA_SPACE:
+		return "SPACE"
+	case TRIVIA_NEWLINE:
+		return "NEWLINE"
+	case TRIVIA_COMMENT:
+		return "COMMENT"
+	case TRIVIA_DOC_COMMENT:
+		return "DOC_COMMENT"
+	case TRIVIA_BOM:
+		return "BOM"
+	}
+	return "UNKNOWN"
+}
+
+// Returns the text of the token along with its leading and trailing trivia.
+// The EOF token contributes only its trivia.
+func (token Token) FullText() string {
+	var text strings.Builder
+	if token.Trivia != nil {
+		for _, piece := range token.Trivia.Leading {
+			text.WriteString(piece.Text)
+		}
+	}
+	if token.TokenType != EOF.TokenType {
+		text.WriteString(token.Image())
+	}
+	if token.Trivia != nil {
+		for _, piece := range token.Trivia.Trailing {
+			text.WriteString(piece.Text)
+		}
+	}
+	return text.String()
+}
+
+// Scans the next token along with its leading and trailing trivia.  Comments
+// are read as (raw) tokens and converted to trivia.  A token that is read while
+// looking for a trailing comment is kept in the lookahead for the next Scan().
+func (reader *lexerState) scanWithTrivia() (Token, error) {
+	trivia := &Trivia{}
+	var token Token
+	var err error
+	for {
+		// A token in the lookahead immediately follows the previous token's trivia.
+		if len(reader.lookahead) == 0 {
+			if reader.skipBOM() {
+				trivia.Leading = append(trivia.Leading, TriviaPiece{TRIVIA_BOM, UTF8_BOM})
+			}
+			trivia.Leading = append(trivia.Leading, reader.readSpaces(false)...)
+		}
+		token, err = reader.nextToken()
+		if err != nil || !isCommentToken(token) {
+			break
+		}
+		trivia.Leading = append(trivia.Leading, TriviaPiece{TRIVIA_COMMENT, token.Image()})
+	}
+	if err != nil {
+		eof := EOF
+		eof.Trivia = trivia
+		return eof, err
+	}
+
+	token = reader.trackSentence(token)
+	trivia.Trailing = reader.readSpaces(true)
+	if reader.startsComment() {
+		next, err := reader.nextToken()
+		if err == nil && isCommentToken(next) {
+			trivia.Trailing = append(trivia.Trailing,
+				TriviaPiece{TRIVIA_COMMENT, next.Image()})
+		} else if err == nil {
+			reader.lookahead = append(reader.lookahead, next)
+		}
+	}
+	token.Trivia = trivia
+	reader.markDocTrivia(token)
+	return token, nil
+}
+
+// Consumes the spaces before the next token as trivia, with a piece for each
+// newline (which may be "\r\n") and for each run of other spaces.  When reading
+// trailing trivia it stops before the first newline.
+func (reader *lexerState) readSpaces(trailing bool) []TriviaPiece {
+	var pieces []TriviaPiece
+	for {
+		r, size := reader.peek()
+		if size == 0 || !unicode.IsSpace(r) || (trailing && isLineBreak(r)) {
+			return pieces
+		}
+		reader.begin()
+		reader.advance(r, size)
+		if r == '\r' && reader.lookingAt("\n") {
+			reader.next()
+		}
+		text := string(reader.image())
+		if isLineBreak(r) {
+			pieces = append(pieces, TriviaPiece{TRIVIA_NEWLINE, text})
+		} else if n := len(pieces); n > 0 && pieces[n-1].Kind == TRIVIA_SPACE {
+			pieces[n-1].Text += text
+		} else {
+			pieces = append(pieces, TriviaPiece{TRIVIA_SPACE, text})
+		}
+	}
+}
+
+// Returns true if the next rune could begin one of the dialect's comments.
+func (reader *lexerState) startsComment() bool {
+	r, size := reader.peek()
+	if size == 0 {
+		return false
+	}
+	for image, optype := range reader.dialect.operators {
+		switch optype.(type) {
+		case commentMarker, blockCommentMarker:
+			if []rune(image)[0] == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isCommentToken(token Token) bool {
+	_, ok := token.TokenType.(*comment)
+	return ok
+}
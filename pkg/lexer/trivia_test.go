@@ -0,0 +1,115 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/trivia_test.go
+
+package lexer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan_TriviaRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		input   string
+	}{
+		{"empty", KIF, ""},
+		{"only spaces", KIF, " \t\n\n  "},
+		{"only comments", HRF, "% one\n\n% two"},
+		{"sentence", KIF, "(role x)"},
+		{"indented", KIF, "(<= (next ?x)\n\t(does ?x noop))  \n\n"},
+		{"comments", KIF, "; header\n(role x) ; trailing\n  ; leading\n(role o);"},
+		{"meta", GEL, "%%! title: Janken\n%% rules\nrole left, right %% players\n"},
+		{"unknown operator", GEL, "a %x b"},
+		{"malformed", KIF, "(role \"x\n) \x01 ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(tt.input),
+				Options{Dialect: tt.dialect, Trivia: true})
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			if len(tokens) == 0 || tokens[len(tokens)-1].TokenType != EOF.TokenType {
+				t.Fatalf("ScanAll() did not end with EOF: %v", tokens)
+			}
+			var text strings.Builder
+			for _, token := range tokens {
+				text.WriteString(token.FullText())
+			}
+			if text.String() != tt.input {
+				t.Errorf("FullText() of tokens = %q, want %q", text.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestScan_TriviaExamples(t *testing.T) {
+	paths, _ := filepath.Glob("../../examples/*.ggd")
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			input, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := ScanAll(strings.NewReader(string(input)),
+				Options{Dialect: GEL, Trivia: true})
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			var text strings.Builder
+			for _, token := range tokens {
+				text.WriteString(token.FullText())
+			}
+			if text.String() != string(input) {
+				t.Errorf("FullText() of tokens does not reproduce %s", path)
+			}
+		})
+	}
+}
+
+func TestScan_TriviaAttachment(t *testing.T) {
+	input := "; header\n\n(role x)  ; the first player\n  x"
+	tokens, err := ScanAll(strings.NewReader(input), Options{Trivia: true})
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	want := []Trivia{
+		{Leading: []TriviaPiece{{TRIVIA_COMMENT, "; header"},
+			{TRIVIA_NEWLINE, "\n"}, {TRIVIA_NEWLINE, "\n"}}},
+		{Trailing: []TriviaPiece{{TRIVIA_SPACE, " "}}},
+		{},
+		{Trailing: []TriviaPiece{{TRIVIA_SPACE, "  "},
+			{TRIVIA_COMMENT, "; the first player"}}},
+		{Leading: []TriviaPiece{{TRIVIA_NEWLINE, "\n"}, {TRIVIA_SPACE, "  "}}},
+		{},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("ScanAll() = %v, want %d tokens", tokens, len(want))
+	}
+	for i, token := range tokens {
+		if !reflect.DeepEqual(*token.Trivia, want[i]) {
+			t.Errorf("token %q trivia = %+v, want %+v", token.Image(), *token.Trivia, want[i])
+		}
+	}
+	if !IsSentence(tokens[1].TokenPos) {
+		t.Errorf("token %v not flagged as in a sentence", tokens[1])
+	}
+}
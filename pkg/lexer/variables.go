@@ -0,0 +1,86 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/variables.go
+
+package lexer
+
+import "unicode/utf8"
+
+const (
+	RUNE_QUESTION_MARK = '?'
+	RUNE_BACKSLASH     = '\\'
+)
+
+// Reads a variable from its pending sigil (`?` or, in GEL, `\`) and the name
+// immediately following it.  A `?` without a name is an UnexpectedToken and is
+// reported as a Diagnostic, while a `\` without a name is the BACKSLASH operator.
+func (reader *lexerState) readVariable() Token {
+	pos := reader.begin()
+	sigil := reader.next()
+
+	if r, size := reader.peek(); size == 0 || !isIdentRune(r) {
+		if sigil == RUNE_BACKSLASH {
+			return BACKSLASH.At(pos)
+		}
+		reader.errorAt(pos, CODE_DANGLING_SIGIL, "dangling '%c' without a variable name", sigil)
+		return UnexpectedToken(string(reader.image()), pos)
+	}
+	reader.skipWhile(isIdentRune)
+	return Token{TokenPos: pos, TokenType: reader.variable(reader.image())}
+}
+
+// A token representing a variable.  The image retains the sigil, if there is
+// one, while the name is the variable's name without it.
+type VariableToken struct {
+	image  string
+	name   string
+	symbol SymbolID
+}
+
+// Constructs a VARIABLE token.  The image may begin with `?` (as in KIF and GEL)
+// or `\` (as in a GEL binding), otherwise the image is the name (as in HRF).
+func Variable(image string, pos TokenPos) Token {
+	name := image
+	if first, _ := utf8.DecodeRuneInString(image); first == RUNE_QUESTION_MARK ||
+		first == RUNE_BACKSLASH {
+		name = image[1:]
+	}
+	return Token{TokenPos: pos, TokenType: &VariableToken{image: image, name: name}}
+}
+func (v *VariableToken) TypeString() string { return "VARIABLE" }
+func (v *VariableToken) Image() string      { return v.image }
+
+// Returns the name of the variable, without its sigil.
+func (v *VariableToken) Name() string { return v.name }
+
+// Returns the SymbolID of the variable's name, see Options.Symbols.
+func (v *VariableToken) Symbol() SymbolID { return v.symbol }
+
+// Token QUE_MARK = "?"
+//
+// Deprecated: the lexer no longer produces QUE_MARK tokens, a `?` and the name
+// following it are scanned as a single VARIABLE token (see Variable), and a `?`
+// without a name is an UnexpectedToken.  Kept for existing code that refers to it.
+type QMarkToken struct{ SymbolToken }
+
+// Deprecated: the lexer produces Variable tokens instead, see QMarkToken.
+func QuestionMark(pos TokenPos) Token {
+	return Token{TokenPos: pos, TokenType: &QUE_MARK}
+}
+func (tok QMarkToken) TypeString() string { return "QUE_MARK" }
+func (tok QMarkToken) Image() string      { return string(RUNE_QUESTION_MARK) }
+
+// Deprecated: the lexer produces Variable tokens instead, see QMarkToken.
+var QUE_MARK QMarkToken
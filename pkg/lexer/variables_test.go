@@ -0,0 +1,120 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/lexer/variables_test.go
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadVariable(t *testing.T) {
+	startPos := NewTokenPos(1, 1)
+	tests := []struct {
+		name            string
+		input           string
+		want            Token
+		wantName        string
+		wantRemaining   string
+		wantDiagnostics []Diagnostic
+	}{
+		{"kif variable", "?x", Variable("?x", startPos), "x", "", nil},
+		{"then space", "?player ", Variable("?player", startPos), "player", " ", nil},
+		{"then paren", "?y)", Variable("?y", startPos), "y", ")", nil},
+		{"with digits", "?x1_b", Variable("?x1_b", startPos), "x1_b", "", nil},
+		{"anonymous", "?_,", Variable("?_", startPos), "_", ",", nil},
+		{"binding", "\\row <-", Variable("\\row", startPos), "row", " <-", nil},
+		{"dangling", "? x", UnexpectedToken("?", startPos), "", " x",
+			[]Diagnostic{{TokenPos: startPos, Code: CODE_DANGLING_SIGIL,
+				Message: "dangling '?' without a variable name"}}},
+		{"dangling at EOF", "?", UnexpectedToken("?", startPos), "", "",
+			[]Diagnostic{{TokenPos: startPos, Code: CODE_DANGLING_SIGIL,
+				Message: "dangling '?' without a variable name"}}},
+		{"lone backslash", "\\ ", BACKSLASH.At(startPos), "", " ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newTestLexer(tt.input, startPos, GEL)
+			got := reader.readVariable()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lexerState.readVariable() = %v, want %v", got, tt.want)
+			}
+			if v, ok := got.TokenType.(*VariableToken); ok && v.Name() != tt.wantName {
+				t.Errorf("VariableToken.Name() = %q, want %q", v.Name(), tt.wantName)
+			}
+			if !reflect.DeepEqual(reader.Diagnostics(), tt.wantDiagnostics) {
+				t.Errorf("lexerState.Diagnostics() = %v, want %v", reader.Diagnostics(), tt.wantDiagnostics)
+			}
+			if rest := reader.remaining(); rest != tt.wantRemaining {
+				t.Errorf("lexerState.readVariable() left %q, want %q",
+					rest, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestScan_Variables(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		input   string
+		want    []string
+	}{
+		{KIF, "(does ?x ?y)", []string{"x", "y"}},
+		{KIF, "(cell \\row)", []string{}},
+		{HRF, "does(Role, play(Hand)) & role(left)", []string{"Role", "Hand"}},
+		{GEL, "?player -> play(?hand)", []string{"player", "hand"}},
+		{GEL, "{[ BLANK | \\row <- 1..3, \\col <- 1..3 ]}", []string{"row", "col"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.String()+" "+tt.input, func(t *testing.T) {
+			tokens, err := ScanAll(strings.NewReader(tt.input), Options{Dialect: tt.dialect})
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+			names := []string{}
+			for _, token := range tokens {
+				if v, ok := token.TokenType.(*VariableToken); ok {
+					names = append(names, v.Name())
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("ScanAll() variables = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariable(t *testing.T) {
+	tests := []struct {
+		image string
+		name  string
+	}{
+		{"?x", "x"},
+		{"\\row", "row"},
+		{"Role", "Role"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			variable := Variable(tt.image, NewTokenPos(1, 1)).TokenType.(*VariableToken)
+			if variable.Image() != tt.image || variable.Name() != tt.name {
+				t.Errorf("Variable(%q) = %q named %q, want named %q",
+					tt.image, variable.Image(), variable.Name(), tt.name)
+			}
+		})
+	}
+}
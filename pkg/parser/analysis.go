@@ -0,0 +1,94 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/analysis.go
+
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Properties of a grammar's rules that are computed without parsing, for tools
+// such as editors (e.g. completing what may come next) and parser generators.
+// Both are keyed by rule name and include the helpers of the normalized grammar.
+type Analysis struct {
+	// The rules that can match the empty string.  A pattern is a terminal here
+	// even if it could match the empty string, as with a pattern like /a*/.
+	Nullable map[string]bool
+	// The literals and patterns that a match of each rule can begin with,
+	// sorted by how they are written.  Empty for rules that only match the
+	// empty string.
+	First map[string][]EarleySymbol
+}
+
+// Computes the nullable rules and FIRST sets of the (normalized) grammar.
+func Analyze(g Grammar) Analysis {
+	rules := Normalize(g).Rules()
+	analysis := Analysis{
+		Nullable: make(map[string]bool),
+		First:    make(map[string][]EarleySymbol),
+	}
+	for name := range findNullable(rules) {
+		analysis.Nullable[name] = true
+	}
+
+	// Terminals are keyed by how they are written, literals being quoted.
+	first := make(map[string]map[string]EarleySymbol)
+	add := func(rule string, key string, symbol EarleySymbol) bool {
+		if _, ok := first[rule][key]; ok {
+			return false
+		}
+		first[rule][key] = symbol
+		return true
+	}
+	for _, rule := range rules {
+		first[rule.name] = make(map[string]EarleySymbol)
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			for _, choice := range rule.choices {
+				for _, symbol := range choice.symbols {
+					matcher, ok := symbol.(RuleMatcher)
+					if !ok {
+						changed = add(rule.name, fmt.Sprint(symbol), symbol) || changed
+						break
+					}
+					for key, terminal := range first[matcher.name] {
+						changed = add(rule.name, key, terminal) || changed
+					}
+					if !analysis.Nullable[matcher.name] {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		keys := make([]string, 0, len(first[rule.name]))
+		for key := range first[rule.name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		terminals := make([]EarleySymbol, len(keys))
+		for i, key := range keys {
+			terminals[i] = first[rule.name][key]
+		}
+		analysis.First[rule.name] = terminals
+	}
+	return analysis
+}
@@ -0,0 +1,484 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/earley.go
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The result of parsing an input, the tree of rules and terminals matching it.
+// See Value() for evaluating the post-processing of the rules in the tree.
+type Result struct {
+	Input string
+	Tree  *Node
+
+	rules map[string][]Choice
+}
+
+// A Node is a rule choice or a terminal matched over Input[Start:End].  For a
+// rule the children are the nodes matching each symbol of the choice, for a
+// terminal the Text is the matched text and, for patterns, Groups holds the
+// text of the whole match followed by the text of each subgroup.
+type Node struct {
+	Rule     string
+	Choice   int
+	Start    int
+	End      int
+	Children []*Node
+
+	Text   string
+	Groups []string
+}
+
+// Returns true if the node matched a literal or pattern rather than a rule.
+func (node *Node) IsTerminal() bool { return node.Rule == "" }
+
+// Formats the tree as nested (rule children...) with terminals quoted.
+func (node *Node) String() string {
+	var text strings.Builder
+	node.format(&text)
+	return text.String()
+}
+
+func (node *Node) format(text *strings.Builder) {
+	if node.IsTerminal() {
+		fmt.Fprintf(text, "%q", node.Text)
+		return
+	}
+	text.WriteString("(" + node.Rule)
+	for _, child := range node.Children {
+		text.WriteString(" ")
+		child.format(text)
+	}
+	text.WriteString(")")
+}
+
+// Reported when the input is not a sentence of the grammar.  The offset is the
+// furthest point in the input that the parser reached, where none of the
+// expected terminals match.
+type SyntaxError struct {
+	Offset   int
+	Line     int
+	Column   int
+	Found    string
+	Expected []string
+}
+
+func (err *SyntaxError) Error() string {
+	found := "end of input"
+	if err.Found != "" {
+		found = fmt.Sprintf("%q", err.Found)
+	}
+	if len(err.Expected) == 0 {
+		return fmt.Sprintf("%d:%d: unexpected %s", err.Line, err.Column, found)
+	}
+	return fmt.Sprintf("%d:%d: unexpected %s, expected %s",
+		err.Line, err.Column, found, strings.Join(err.Expected, " or "))
+}
+
+// Parses the input as the grammar's start rule, using Earley's algorithm.  The
+// parser is scannerless: literals and patterns are matched directly against the
+// input text, a pattern matching the longest text that its regular expression
+// prefers at that position.  Any grammar can be parsed, including left- and
+// right-recursive rules and rules that match the empty string.  If the input is
+// ambiguous one of its parse trees is returned, see ParseForest() for all of
+// them.  Kleene operators and groups in the grammar are replaced by helper rules
+// first, see Normalize().
+func Parse(g Grammar, input string) (Result, error) {
+	parser, err := newEarley(Normalize(g))
+	if err != nil {
+		return Result{}, err
+	}
+	final, err := parser.parse(input)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{input, parser.node(final), parser.rules}, nil
+}
+
+// The rules of a grammar prepared for parsing: choices indexed by rule name,
+// patterns compiled, keywords indexed and the rules that can match the empty
+// string.
+type earley struct {
+	start    string
+	rules    map[string][]Choice
+	patterns map[string]*regexp.Regexp
+	keywords map[string]bool
+
+	// For each nullable rule, a choice deriving the empty string.  Following
+	// these choices always terminates, see findNullable().
+	nullable map[string]int
+
+	input string
+	sets  []*itemSet
+}
+
+func newEarley(g Grammar) (*earley, error) {
+	if g.Start() == "" {
+		return nil, fmt.Errorf("grammar has no rules")
+	}
+	parser := &earley{
+		start:    g.Start(),
+		rules:    make(map[string][]Choice),
+		patterns: make(map[string]*regexp.Regexp),
+		keywords: make(map[string]bool),
+	}
+	for _, keyword := range g.Keywords() {
+		parser.keywords[keyword] = true
+	}
+	for _, rule := range g.Rules() {
+		parser.rules[rule.name] = append(parser.rules[rule.name], rule.choices...)
+		for _, choice := range rule.choices {
+			for _, symbol := range choice.symbols {
+				if err := parser.compile(symbol); err != nil {
+					return nil, fmt.Errorf("rule %s: %w", rule.name, err)
+				}
+			}
+		}
+	}
+	parser.nullable = findNullable(g.Rules())
+	return parser, nil
+}
+
+// Compiles a pattern, anchored so that it only matches at the parse position.
+func (parser *earley) compile(symbol EarleySymbol) error {
+	pattern, ok := symbol.(PatternMatcher)
+	if !ok {
+		return nil
+	}
+	if _, ok := parser.patterns[pattern.pattern]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern.pattern + `)`)
+	if err != nil {
+		return fmt.Errorf("invalid pattern /%s/: %w", pattern.pattern, err)
+	}
+	parser.patterns[pattern.pattern] = re
+	return nil
+}
+
+// Finds the rules that derive the empty string, those with a choice made only
+// of nullable rules (or no symbols at all), and returns the index of such a
+// choice for each of them.  Rules are visited in grammar order until no more are
+// found, so the choice recorded for each rule only refers to rules that were
+// found before it and the empty derivation is always finite.
+func findNullable(rules []EarleyRule) map[string]int {
+	nullable := make(map[string]int)
+	allNullable := func(symbols []EarleySymbol) bool {
+		for _, symbol := range symbols {
+			matcher, ok := symbol.(RuleMatcher)
+			if !ok {
+				return false
+			}
+			if _, ok := nullable[matcher.name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	for found := true; found; {
+		found = false
+		for _, rule := range rules {
+			if _, ok := nullable[rule.name]; ok {
+				continue
+			}
+			for i, choice := range rule.choices {
+				if allNullable(choice.symbols) {
+					nullable[rule.name] = i
+					found = true
+					break
+				}
+			}
+		}
+	}
+	return nullable
+}
+
+// An Earley item, a choice of a rule with the dot before symbols[dot], begun at
+// the origin offset in the input.
+type item struct {
+	rule   string
+	choice int
+	dot    int
+	origin int
+}
+
+// An item in the set at offset end, with the links that added it.  An item is
+// added once but it may be reached in more than one way when the input is
+// ambiguous, and each of these is kept for building the parse forest.  Parse()
+// only follows the first link of each item.
+type state struct {
+	item
+	end   int
+	links []link
+}
+
+// When the dot has advanced, prev is the item before it and the symbol it
+// advanced over was matched by either a completed child item or a terminal
+// token.  When both are nil, the symbol is a nullable rule matching the empty
+// string.
+type link struct {
+	prev  *state
+	child *state
+	token *Node
+}
+
+// The items at an offset in the input.  Items waiting for a rule to complete
+// are indexed by the rule's name, and rules that completed here without
+// consuming any input are remembered for items predicted after they completed.
+type itemSet struct {
+	states  []*state
+	index   map[item]*state
+	waiting map[string][]*state
+	empty   map[string]*state
+}
+
+// Fills the item sets for the input and returns the start rule's item that was
+// completed over all of it, or a syntax error.
+func (parser *earley) parse(input string) (*state, error) {
+	parser.input = input
+	parser.sets = make([]*itemSet, len(input)+1)
+	for i := range parser.rules[parser.start] {
+		parser.add(0, item{parser.start, i, 0, 0}, nil, nil, nil)
+	}
+
+	for offset, set := range parser.sets {
+		if set == nil {
+			continue
+		}
+		// Items may be added to the set while it is being processed.
+		for i := 0; i < len(set.states); i++ {
+			current := set.states[i]
+			symbols := parser.symbols(current.item)
+			if current.dot == len(symbols) {
+				parser.complete(offset, current)
+				continue
+			}
+			switch symbol := symbols[current.dot].(type) {
+			case RuleMatcher:
+				parser.predict(offset, current, symbol.name)
+			case LiteralMatcher:
+				if strings.HasPrefix(input[offset:], symbol.image) {
+					end := offset + len(symbol.image)
+					parser.advance(end, current, nil, &Node{
+						Start: offset, End: end, Text: symbol.image})
+				}
+			case PatternMatcher:
+				if token := parser.match(offset, symbol); token != nil {
+					parser.advance(token.End, current, nil, token)
+				}
+			}
+		}
+	}
+
+	if final := parser.accepted(); final != nil {
+		return final, nil
+	}
+	return nil, parser.syntaxError()
+}
+
+func (parser *earley) symbols(it item) []EarleySymbol {
+	return parser.rules[it.rule][it.choice].symbols
+}
+
+// Adds the item to the set at offset, or its link if the set already has it.
+func (parser *earley) add(offset int, it item, prev, child *state, token *Node) {
+	set := parser.sets[offset]
+	if set == nil {
+		set = &itemSet{
+			index:   make(map[item]*state),
+			waiting: make(map[string][]*state),
+			empty:   make(map[string]*state),
+		}
+		parser.sets[offset] = set
+	}
+	if existing, ok := set.index[it]; ok {
+		if prev != nil {
+			existing.links = append(existing.links, link{prev, child, token})
+		}
+		return
+	}
+	added := &state{item: it, end: offset}
+	if prev != nil {
+		added.links = []link{{prev, child, token}}
+	}
+	set.index[it] = added
+	set.states = append(set.states, added)
+}
+
+// Adds the item that follows current, with its dot over the next symbol.
+func (parser *earley) advance(offset int, current, child *state, token *Node) {
+	next := current.item
+	next.dot++
+	parser.add(offset, next, current, child, token)
+}
+
+// Predicts each choice of the named rule at this offset.  If the rule can match
+// the empty string then the current item is also advanced over it here, as the
+// completion of an empty rule may have happened before current was added.
+func (parser *earley) predict(offset int, current *state, name string) {
+	set := parser.sets[offset]
+	set.waiting[name] = append(set.waiting[name], current)
+	for i := range parser.rules[name] {
+		parser.add(offset, item{name, i, 0, offset}, nil, nil, nil)
+	}
+	if completed, ok := set.empty[name]; ok {
+		parser.advance(offset, current, completed, nil)
+	} else if _, ok := parser.nullable[name]; ok {
+		parser.advance(offset, current, nil, nil)
+	}
+}
+
+// Advances the items that were waiting at the completed item's origin.
+func (parser *earley) complete(offset int, completed *state) {
+	if completed.origin == offset {
+		set := parser.sets[offset]
+		if _, ok := set.empty[completed.rule]; !ok {
+			set.empty[completed.rule] = completed
+		}
+	}
+	waiting := parser.sets[completed.origin].waiting[completed.rule]
+	for i := 0; i < len(waiting); i++ {
+		parser.advance(offset, waiting[i], completed, nil)
+	}
+}
+
+// Returns the pattern's match at offset, if there is one and it is not one of
+// the grammar's keywords.
+func (parser *earley) match(offset int, pattern PatternMatcher) *Node {
+	text := parser.input[offset:]
+	loc := parser.patterns[pattern.pattern].FindStringSubmatchIndex(text)
+	if loc == nil || parser.keywords[text[:loc[1]]] {
+		return nil
+	}
+	groups := make([]string, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return &Node{Start: offset, End: offset + loc[1], Text: groups[0], Groups: groups}
+}
+
+// Returns the start rule's item completed over the whole input, if any.
+func (parser *earley) accepted() *state {
+	set := parser.sets[len(parser.input)]
+	if set == nil {
+		return nil
+	}
+	for _, final := range set.states {
+		if final.rule == parser.start && final.origin == 0 &&
+			final.dot == len(parser.symbols(final.item)) {
+			return final
+		}
+	}
+	return nil
+}
+
+// Builds the parse tree of a completed item by following the first link of each
+// item back from its last symbol to its first.
+func (parser *earley) node(completed *state) *Node {
+	node := &Node{
+		Rule:     completed.rule,
+		Choice:   completed.choice,
+		Start:    completed.origin,
+		End:      completed.end,
+		Children: make([]*Node, completed.dot),
+	}
+	symbols := parser.symbols(completed.item)
+	for current := completed; current.dot > 0; current = current.links[0].prev {
+		var child *Node
+		switch first := current.links[0]; {
+		case first.token != nil:
+			child = first.token
+		case first.child != nil:
+			child = parser.node(first.child)
+		default:
+			child = parser.emptyNode(symbols[current.dot-1].(RuleMatcher).name, current.end)
+		}
+		node.Children[current.dot-1] = child
+	}
+	return node
+}
+
+// Builds the tree of a nullable rule matching the empty string at offset.
+func (parser *earley) emptyNode(name string, offset int) *Node {
+	choice := parser.nullable[name]
+	symbols := parser.rules[name][choice].symbols
+	node := &Node{
+		Rule:     name,
+		Choice:   choice,
+		Start:    offset,
+		End:      offset,
+		Children: make([]*Node, len(symbols)),
+	}
+	for i, symbol := range symbols {
+		node.Children[i] = parser.emptyNode(symbol.(RuleMatcher).name, offset)
+	}
+	return node
+}
+
+// Describes where parsing stopped, at the last offset that any item reached,
+// and which literals and patterns could have continued from there.  If nothing
+// was predicted at all, the start rule has no choices to parse the input with.
+func (parser *earley) syntaxError() error {
+	offset := len(parser.sets) - 1
+	for offset > 0 && parser.sets[offset] == nil {
+		offset--
+	}
+	if parser.sets[offset] == nil {
+		return fmt.Errorf("start rule %s has no choices", parser.start)
+	}
+	seen := make(map[string]bool)
+	var expected []string
+	for _, current := range parser.sets[offset].states {
+		symbols := parser.symbols(current.item)
+		if current.dot == len(symbols) {
+			continue
+		}
+		symbol := symbols[current.dot]
+		if _, ok := symbol.(RuleMatcher); ok {
+			continue
+		}
+		name := fmt.Sprint(symbol)
+		if !seen[name] {
+			seen[name] = true
+			expected = append(expected, name)
+		}
+	}
+	sort.Strings(expected)
+
+	line, column := position(parser.input, offset)
+	found := ""
+	if offset < len(parser.input) {
+		r, _ := utf8.DecodeRuneInString(parser.input[offset:])
+		found = string(r)
+	}
+	return &SyntaxError{offset, line, column, found, expected}
+}
+
+// Returns the 1-based line and column (in runes) of a byte offset in the text.
+func position(text string, offset int) (line, column int) {
+	before := text[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
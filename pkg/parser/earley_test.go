@@ -0,0 +1,238 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/earley_test.go
+
+package parser
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// Builds a grammar from rule specs, the first being the start rule.
+func testGrammar(rules ...RuleSpec) Grammar {
+	g := NewGrammar()
+	for _, rule := range rules {
+		g.AddRule(EarleyRule{rule.name, []Choice{{rule.symbols, rule.arrange}}})
+	}
+	return g
+}
+
+// A grammar whose start rule has no choices, as only the Grammar interface can
+// make (a rule in a grammar file has at least one).
+func noChoicesGrammar() Grammar {
+	g := NewGrammar()
+	g.AddRule(EarleyRule{name: "a"})
+	return g
+}
+
+var (
+	// Left-recursive sums of numbers.
+	sumGrammar = testGrammar(
+		RuleSpec{"sum", spec(s{"sum"}, l{"+"}, s{"num"}), all},
+		RuleSpec{"sum", spec(s{"num"}), all},
+		RuleSpec{"num", spec(p{"[0-9]+"}), all},
+	)
+	// Right-recursive lists of words.
+	listGrammar = testGrammar(
+		RuleSpec{"list", spec(s{"word"}, l{","}, s{"list"}), all},
+		RuleSpec{"list", spec(s{"word"}), all},
+		RuleSpec{"word", spec(p{"[a-z]+"}), all},
+	)
+	// Optional and required spacing, as in the EarleyBNF grammar.
+	spacingGrammar = testGrammar(
+		RuleSpec{"pair", spec(s{"_"}, s{"word"}, s{"__"}, s{"word"}, s{"_"}), all},
+		RuleSpec{"_", spec(s{"__$1"}), all},
+		RuleSpec{"__$1", spec(s{"__"}), first},
+		RuleSpec{"__$1", spec(), Nothing{}},
+		RuleSpec{"__", spec(s{"__$1"}, s{"SPACING"}), all},
+		RuleSpec{"SPACING", spec(p{`\s+`}), all},
+		RuleSpec{"word", spec(p{"[a-z]+"}), all},
+	)
+	// Nullable rules that are only nullable through other rules.
+	nullableGrammar = testGrammar(
+		RuleSpec{"top", spec(s{"a"}, l{";"}, s{"a"}), all},
+		RuleSpec{"a", spec(s{"b"}, s{"b"}), all},
+		RuleSpec{"b", spec(l{"x"}), all},
+		RuleSpec{"b", spec(s{"c"}), all},
+		RuleSpec{"c", spec(), all},
+	)
+	// A pattern that may match the empty string.
+	emptyPatternGrammar = testGrammar(
+		RuleSpec{"top", spec(l{"<"}, s{"xs"}, s{"xs"}, l{">"}), all},
+		RuleSpec{"xs", spec(p{"x*"}), all},
+	)
+	// Names that exclude a keyword, which is only matched as a literal.
+	keywordGrammar = func() Grammar {
+		g := testGrammar(
+			RuleSpec{"stmt", spec(l{"if"}, l{" "}, s{"name"}), all},
+			RuleSpec{"stmt", spec(s{"name"}), all},
+			RuleSpec{"name", spec(p{"[a-z]+"}), all},
+		)
+		g.AddKeyword("if")
+		return g
+	}()
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar Grammar
+		input   string
+		want    string
+	}{
+		{"single number", sumGrammar, "12", `(sum (num "12"))`},
+		{"left recursion", sumGrammar, "1+2+3",
+			`(sum (sum (sum (num "1")) "+" (num "2")) "+" (num "3"))`},
+		{"right recursion", listGrammar, "a,b,c",
+			`(list (word "a") "," (list (word "b") "," (list (word "c"))))`},
+		{"no optional spacing", spacingGrammar, "a b",
+			`(pair (_ (__$1)) (word "a") (__ (__$1) (SPACING " ")) ` +
+				`(word "b") (_ (__$1)))`},
+		{"optional spacing", spacingGrammar, " a\n b ",
+			`(pair (_ (__$1 (__ (__$1) (SPACING " ")))) (word "a") ` +
+				`(__ (__$1) (SPACING "\n ")) (word "b") ` +
+				`(_ (__$1 (__ (__$1) (SPACING " ")))))`},
+		{"nullable through rules", nullableGrammar, ";",
+			`(top (a (b (c)) (b (c))) ";" (a (b (c)) (b (c))))`},
+		{"nullable mixed with terminals", nullableGrammar, ";xx",
+			`(top (a (b (c)) (b (c))) ";" (a (b "x") (b "x")))`},
+		{"empty pattern match", emptyPatternGrammar, "<>",
+			`(top "<" (xs "") (xs "") ">")`},
+		{"greedy pattern match", emptyPatternGrammar, "<xx>",
+			`(top "<" (xs "xx") (xs "") ">")`},
+		{"keyword literal", keywordGrammar, "if x",
+			`(stmt "if" " " (name "x"))`},
+		{"name beginning with keyword", keywordGrammar, "iffy",
+			`(stmt (name "iffy"))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.grammar, tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := result.Tree.String(); got != tt.want {
+				t.Errorf("Parse() tree\n  got %s\n want %s", got, tt.want)
+			}
+			if result.Tree.Start != 0 || result.Tree.End != len(tt.input) {
+				t.Errorf("Parse() tree spans [%d, %d), want [0, %d)",
+					result.Tree.Start, result.Tree.End, len(tt.input))
+			}
+		})
+	}
+}
+
+func TestParse_PatternGroups(t *testing.T) {
+	g := testGrammar(
+		RuleSpec{"pair", spec(p{`(\w+)=(\w+)?`}), all},
+	)
+	result, err := Parse(g, "key=")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	token := result.Tree.Children[0]
+	if !token.IsTerminal() {
+		t.Fatalf("Parse() child %v is not a terminal", token)
+	}
+	if want := []string{"key=", "key", ""}; !reflect.DeepEqual(token.Groups, want) {
+		t.Errorf("Parse() pattern groups = %q, want %q", token.Groups, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar Grammar
+		input   string
+		want    string
+	}{
+		{"unexpected literal", sumGrammar, "1+2-3",
+			`1:4: unexpected "-", expected "+"`},
+		{"unexpected end", sumGrammar, "1+",
+			`1:3: unexpected end of input, expected /[0-9]+/`},
+		{"position on later line", spacingGrammar, "a\n b c",
+			`2:4: unexpected "c", expected /\s+/`},
+		{"empty input", listGrammar, "",
+			`1:1: unexpected end of input, expected /[a-z]+/`},
+		{"keyword as name", keywordGrammar, "if if",
+			`1:4: unexpected "i", expected /[a-z]+/`},
+		{"keyword alone", keywordGrammar, "if",
+			`1:3: unexpected end of input, expected " "`},
+		{"empty grammar", NewGrammar(), "x", "grammar has no rules"},
+		{"start rule without choices", noChoicesGrammar(), "x",
+			"start rule a has no choices"},
+		{"invalid pattern", testGrammar(RuleSpec{"x", spec(p{"[a-"}), all}), "x",
+			"rule x: invalid pattern /[a-/: error parsing regexp: " +
+				"invalid character class range: `a-)`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.grammar, tt.input)
+			if err == nil {
+				t.Fatalf("Parse() succeeded, want error %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse() error\n  got %s\n want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_EarleyBNF(t *testing.T) {
+	inputs := []string{
+		`sum ::= sum "+" num | num`,
+		"(* comment *)\nword ::= /[a-z]+/ => \\0\n",
+		`list ::= item _ "," _ list => [\1, \5...] | item`,
+		`pair ::= key ":" value => Pair{ key: \1, value: \3 }`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			result, err := Parse(EarleyBNFGrammar(), input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if result.Tree.Rule != "input" {
+				t.Errorf("Parse() tree rule = %s, want input", result.Tree.Rule)
+			}
+		})
+	}
+}
+
+func TestParse_EarleyBNFGrammarFile(t *testing.T) {
+	data, err := os.ReadFile("../../grammar/earleybnf.grammar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Parse(EarleyBNFGrammar(), string(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if result.Tree.End != len(data) {
+		t.Errorf("Parse() tree ends at %d, want %d", result.Tree.End, len(data))
+	}
+}
+
+func BenchmarkParse_EarleyBNF(b *testing.B) {
+	data, err := os.ReadFile("../../grammar/earleybnf.grammar")
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := EarleyBNFGrammar()
+	for i := 0; i < b.N; i++ {
+		Parse(g, string(data))
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/evaluate.go
+
+package parser
+
+import "fmt"
+
+// Evaluates the post-processing of the parse tree, from its leaves up to its
+// root, and returns the root's value.
+//
+// Each rule's value is the projection (its Choice.arrange) of the values of its
+// symbols, where literals and patterns have the text they matched as their value.
+// In the projection \1 refers to the first symbol's value, \2 to the second and
+// so on, while \0 refers to the list of all of them and is used when the choice
+// has no arrange.  A choice made only of a pattern instead projects the pattern's
+// match, with \0 referring to the whole match and \1 etc. to its subgroups.
+func (result Result) Value() (Value, error) {
+	eval := evaluator{result.Input, result.rules}
+	return eval.value(result.Tree)
+}
+
+type evaluator struct {
+	input string
+	rules map[string][]Choice
+}
+
+// The values that a projection's references select from.
+type scope struct {
+	node  *Node
+	whole Value
+	items []Value
+}
+
+func (eval evaluator) value(node *Node) (Value, error) {
+	if node.IsTerminal() {
+		return node.Text, nil
+	}
+	choice := eval.rules[node.Rule][node.Choice]
+	if _, ok := onlyPattern(choice); ok {
+		token := node.Children[0]
+		items := make([]Value, len(token.Groups)-1)
+		for i, group := range token.Groups[1:] {
+			items[i] = group
+		}
+		return eval.project(choice.arrange, scope{node, token.Text, items})
+	}
+
+	items := make(List, len(node.Children))
+	for i, child := range node.Children {
+		value, err := eval.value(child)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = value
+	}
+	return eval.project(choice.arrange, scope{node, items, items})
+}
+
+// Returns the choice's pattern if the pattern is its only symbol.
+func onlyPattern(choice Choice) (PatternMatcher, bool) {
+	if len(choice.symbols) != 1 {
+		return PatternMatcher{}, false
+	}
+	pattern, ok := choice.symbols[0].(PatternMatcher)
+	return pattern, ok
+}
+
+func (eval evaluator) project(arrange PostProcessing, values scope) (Value, error) {
+	switch arrange := arrange.(type) {
+	case nil:
+		return values.whole, nil
+	case Nothing:
+		return nil, nil
+	case StringProjection:
+		return arrange.value, nil
+	case BooleanProjection:
+		return arrange.value, nil
+	case ItemProjection:
+		return eval.item(values, arrange.ref)
+	case ExpandList:
+		return nil, eval.errorf(values,
+			"\\%d... can only be expanded within a list", arrange.ref)
+	case ElementGetter:
+		return eval.element(values, arrange)
+	case PropertyGetter:
+		return eval.property(values, arrange)
+	case LengthGetter:
+		return eval.length(values, arrange)
+	case ListProjection:
+		return eval.list(values, arrange)
+	case RecordProjection:
+		return eval.record(values, arrange)
+	}
+	return nil, eval.errorf(values, "unknown post-processing %T", arrange)
+}
+
+// Returns the value referenced by \ref.
+func (eval evaluator) item(values scope, ref int) (Value, error) {
+	if ref == 0 {
+		return values.whole, nil
+	}
+	if ref < 0 || ref > len(values.items) {
+		return nil, eval.errorf(values,
+			"reference \\%d is out of range for %d items", ref, len(values.items))
+	}
+	return values.items[ref-1], nil
+}
+
+// Returns an element of a list, indexed from 1 like references are.
+func (eval evaluator) element(values scope, getter ElementGetter) (Value, error) {
+	value, err := eval.project(getter.from, values)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := value.(List)
+	if !ok {
+		return nil, eval.errorf(values, "%s indexes a %s, not a list",
+			projectionPath(getter), describeValue(value))
+	}
+	if getter.index < 1 || getter.index > len(list) {
+		return nil, eval.errorf(values, "index %s is out of range for %d items",
+			projectionPath(getter), len(list))
+	}
+	return list[getter.index-1], nil
+}
+
+// Returns an attribute of a record.
+func (eval evaluator) property(values scope, getter PropertyGetter) (Value, error) {
+	value, err := eval.project(getter.from, values)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := value.(*Record)
+	if !ok {
+		return nil, eval.errorf(values, "%s gets a property of a %s, not a record",
+			projectionPath(getter), describeValue(value))
+	}
+	property, ok := record.Get(getter.name)
+	if !ok {
+		return nil, eval.errorf(values, "record %s has no attribute %s",
+			record.Name, getter.name)
+	}
+	return property, nil
+}
+
+// Returns the number of elements of a list, zero for null.
+func (eval evaluator) length(values scope, getter LengthGetter) (Value, error) {
+	value, err := eval.project(getter.from, values)
+	if err != nil {
+		return nil, err
+	}
+	switch value := value.(type) {
+	case nil:
+		return 0, nil
+	case List:
+		return len(value), nil
+	}
+	return nil, eval.errorf(values, "%s is the length of a %s, not a list",
+		projectionPath(getter), describeValue(value))
+}
+
+// Formats a reference and the getters applied to it, e.g. \1.name.2
+func projectionPath(arrange PostProcessing) string {
+	switch arrange := arrange.(type) {
+	case ItemProjection:
+		return fmt.Sprintf("\\%d", arrange.ref)
+	case PropertyGetter:
+		return projectionPath(arrange.from) + "." + arrange.name
+	case ElementGetter:
+		return fmt.Sprintf("%s.%d", projectionPath(arrange.from), arrange.index)
+	case LengthGetter:
+		return "len(" + projectionPath(arrange.from) + ")"
+	}
+	return fmt.Sprintf("%T", arrange)
+}
+
+// Constructs a list, splicing in the items of expanded lists.  An expanded null
+// (e.g. the value of an optional rule that matched nothing) adds no items.
+func (eval evaluator) list(values scope, projection ListProjection) (Value, error) {
+	list := List{}
+	for _, item := range projection.values {
+		expand, ok := item.(ExpandList)
+		if !ok {
+			value, err := eval.project(item, values)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			continue
+		}
+		value, err := eval.item(values, expand.ref)
+		if err != nil {
+			return nil, err
+		}
+		switch value := value.(type) {
+		case nil:
+		case List:
+			list = append(list, value...)
+		default:
+			return nil, eval.errorf(values, "\\%d... expands a %s, not a list",
+				expand.ref, describeValue(value))
+		}
+	}
+	return list, nil
+}
+
+// Constructs a record, copying in the attributes of expanded records.  As with
+// lists, an expanded null adds no attributes.
+func (eval evaluator) record(values scope, projection RecordProjection) (Value, error) {
+	record := NewRecord(projection.name)
+	for _, attribute := range projection.attrs {
+		switch attribute := attribute.(type) {
+		case KeyValue:
+			value, err := eval.project(attribute.value, values)
+			if err != nil {
+				return nil, err
+			}
+			record.Set(attribute.key, value)
+		case ExpandRecord:
+			value, err := eval.item(values, attribute.ref)
+			if err != nil {
+				return nil, err
+			}
+			switch value := value.(type) {
+			case nil:
+			case *Record:
+				for _, key := range value.keys {
+					record.Set(key, value.values[key])
+				}
+			default:
+				return nil, eval.errorf(values, "\\%d... expands a %s into record %s",
+					attribute.ref, describeValue(value), projection.name)
+			}
+		}
+	}
+	return record, nil
+}
+
+// Reports an error in the projection of a rule, at the start of its match.
+func (eval evaluator) errorf(values scope, format string, args ...any) error {
+	line, column := position(eval.input, values.node.Start)
+	return fmt.Errorf("%d:%d: %s: %s", line, column, values.node.Rule,
+		fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/evaluate_test.go
+
+package parser
+
+import "testing"
+
+// A grammar of `key=value` assignments with optional `@tags`, parsed from
+// `<top>` for each of the projections in the tests below.
+func projectionGrammar(top PostProcessing) Grammar {
+	return testGrammar(
+		RuleSpec{"top", spec(s{"assign"}, s{"tags"}), top},
+		RuleSpec{"assign", spec(s{"key"}, l{"="}, s{"value"}),
+			rproj("Assign", kv{"key", first}, kv{"value", third})},
+		RuleSpec{"key", spec(p{"[a-z]+"}), all},
+		RuleSpec{"value", spec(p{`"([^"]*)"|([0-9]+)`}), ref{1}},
+		RuleSpec{"value", spec(p{`#([0-9]+)`}), all},
+		RuleSpec{"tags", spec(s{"tags"}, s{"tag"}), lproj(first_cat, second)},
+		RuleSpec{"tags", spec(), Nothing{}},
+		RuleSpec{"tag", spec(l{"@"}, p{"[a-z]+"}), second},
+	)
+}
+
+func TestResult_Value(t *testing.T) {
+	tests := []struct {
+		name  string
+		top   PostProcessing
+		input string
+		want  string
+	}{
+		{"default whole list", nil, `x="y"@a@b`,
+			`[Assign{key: "x", value: "y"}, ["a", "b"]]`},
+		{"whole list reference", all, `x="y"`,
+			`[Assign{key: "x", value: "y"}, null]`},
+		{"item reference", first, `x=#12`, `Assign{key: "x", value: "#12"}`},
+		{"pattern subgroup", first, `x=12`, `Assign{key: "x", value: ""}`},
+		{"nothing", Nothing{}, `x="y"`, `null`},
+		{"string", str{"s"}, `x="y"`, `"s"`},
+		{"expand list", lproj(str{"tags"}, ExpandList{second}), `x="y"@a@b`,
+			`["tags", "a", "b"]`},
+		{"expand null list", lproj(str{"tags"}, ExpandList{second}), `x="y"`,
+			`["tags"]`},
+		{"expand record", rproj("Tagged", ExpandRecord{first}, kv{"tags", second}),
+			`k="v"@t`, `Tagged{key: "k", value: "v", tags: ["t"]}`},
+		{"replace expanded attribute", rproj("A", ExpandRecord{first}, kv{"key", str{"z"}}),
+			`k="v"`, `A{key: "z", value: "v"}`},
+		{"property", lproj(get{first, "value"}, get{first, "key"}), `k="v"`,
+			`["v", "k"]`},
+		{"element", ElementGetter{second, 2}, `k="v"@a@b`, `"b"`},
+		{"length", lproj(LengthGetter{second}, LengthGetter{all}), `k="v"@a@b`,
+			`[2, 2]`},
+		{"length of null", LengthGetter{second}, `k="v"`, `0`},
+		{"booleans", lproj(BooleanProjection{true}, BooleanProjection{false}), `k="v"`,
+			`[true, false]`},
+		{"anonymous record", rproj("", ExpandRecord{first}), `k="v"`,
+			`{key: "k", value: "v"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(projectionGrammar(tt.top), tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			value, err := result.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if got := formatValue(value); got != tt.want {
+				t.Errorf("Value()\n  got %s\n want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_Value_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		top   PostProcessing
+		input string
+		want  string
+	}{
+		{"reference out of range", ref{3}, `x="y"`,
+			`1:1: top: reference \3 is out of range for 2 items`},
+		{"expand outside list", ExpandList{second}, `x="y"`,
+			`1:1: top: \2... can only be expanded within a list`},
+		{"expand record as list", lproj(ExpandList{first}), `x="y"`,
+			`1:1: top: \1... expands a record Assign, not a list`},
+		{"expand list as record", rproj("R", ExpandRecord{second}), `x="y"@a`,
+			`1:1: top: \2... expands a list into record R`},
+		{"property of list", get{second, "name"}, `x="y"@a`,
+			`1:1: top: \2.name gets a property of a list, not a record`},
+		{"missing property", get{first, "name"}, `x="y"`,
+			`1:1: top: record Assign has no attribute name`},
+		{"element of record", ElementGetter{first, 1}, `x="y"`,
+			`1:1: top: \1.1 indexes a record Assign, not a list`},
+		{"element out of range", ElementGetter{second, 2}, `x="y"@a`,
+			`1:1: top: index \2.2 is out of range for 1 items`},
+		{"length of record", LengthGetter{first}, `x="y"`,
+			`1:1: top: len(\1) is the length of a record Assign, not a list`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(projectionGrammar(tt.top), tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			value, err := result.Value()
+			if err == nil {
+				t.Fatalf("Value() = %v, want error %q", value, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Value() error\n  got %s\n want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_Value_PatternError(t *testing.T) {
+	g := testGrammar(
+		RuleSpec{"top", spec(l{"\n"}, s{"word"}), second},
+		RuleSpec{"word", spec(p{"([a-z]+)"}), ref{2}},
+	)
+	result, err := Parse(g, "\nabc")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := `2:1: word: reference \2 is out of range for 1 items`
+	if _, err := result.Value(); err == nil || err.Error() != want {
+		t.Errorf("Value() error = %v, want %s", err, want)
+	}
+}
+
+func TestResult_Value_EarleyBNF(t *testing.T) {
+	input := `word ::= /[a-z]+/ => \1`
+	want := `[EarleyRule{name: "word", choices: [` +
+		`Choice{symbols: [PatternMatcher{pattern: "/[a-z]+/"}], ` +
+		`arrange: ItemProjection{ref: "1"}}]}]`
+	result, err := Parse(EarleyBNFGrammar(), input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	value, err := result.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if got := formatValue(value); got != want {
+		t.Errorf("Value()\n  got %s\n want %s", got, want)
+	}
+}
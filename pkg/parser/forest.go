@@ -0,0 +1,414 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/forest.go
+
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A Forest is a shared packed parse forest, all of the parse trees of an input
+// in one graph.  Each rule that matched a span of the input has one node, which
+// is shared by all the trees that match the rule over that span, and the node's
+// alternatives are the ways that the rule matched it.  A node with more than one
+// alternative is an ambiguity.  The forest is cyclic when a rule derives itself
+// over the same span (e.g. `a ::= a | "x"`), and then has infinitely many trees.
+type Forest struct {
+	Input string
+	Root  *ForestNode
+
+	rules map[string][]Choice
+}
+
+// A ForestNode is a rule or a terminal matched over Input[Start:End].  As with
+// a Node, a terminal has the Text it matched and, for patterns, its Groups.
+type ForestNode struct {
+	Rule         string
+	Start        int
+	End          int
+	Alternatives []*PackedNode
+
+	Text   string
+	Groups []string
+}
+
+// One of the ways a rule matched its span: the choice of the rule and the nodes
+// matching each of the choice's symbols.
+type PackedNode struct {
+	Choice   int
+	Children []*ForestNode
+}
+
+// Returns true if the node matched a literal or pattern rather than a rule.
+func (node *ForestNode) IsTerminal() bool { return node.Rule == "" }
+
+// Parses the input as the grammar's start rule, as Parse() does, and returns
+// the forest of all of its parse trees.
+func ParseForest(g Grammar, input string) (*Forest, error) {
+	parser, err := newEarley(Normalize(g))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parser.parse(input); err != nil {
+		return nil, err
+	}
+	builder := newForestBuilder(parser)
+	root := builder.symbol(parser.start, 0, len(input))
+	return &Forest{input, root, parser.rules}, nil
+}
+
+// A rule, or the text of a terminal symbol, matched over [start, end).
+type span struct {
+	symbol string
+	start  int
+	end    int
+}
+
+// Builds the forest from the links of the parser's items, with a node for each
+// rule's span that was completed and each terminal that was matched.
+type forestBuilder struct {
+	parser    *earley
+	completed map[span][]*state
+	nodes     map[span]*ForestNode
+	terminals map[span]*ForestNode
+	sequences map[*state][][]*ForestNode
+}
+
+func newForestBuilder(parser *earley) *forestBuilder {
+	builder := &forestBuilder{
+		parser:    parser,
+		completed: make(map[span][]*state),
+		nodes:     make(map[span]*ForestNode),
+		terminals: make(map[span]*ForestNode),
+		sequences: make(map[*state][][]*ForestNode),
+	}
+	for _, set := range parser.sets {
+		if set == nil {
+			continue
+		}
+		for _, current := range set.states {
+			if current.dot == len(parser.symbols(current.item)) {
+				key := span{current.rule, current.origin, current.end}
+				builder.completed[key] = append(builder.completed[key], current)
+			}
+		}
+	}
+	return builder
+}
+
+// Returns the node of a rule matched over [start, end).  The node is added
+// before its alternatives are, so that a rule deriving itself refers to it.
+func (builder *forestBuilder) symbol(rule string, start, end int) *ForestNode {
+	key := span{rule, start, end}
+	if node, ok := builder.nodes[key]; ok {
+		return node
+	}
+	node := &ForestNode{Rule: rule, Start: start, End: end}
+	builder.nodes[key] = node
+
+	seen := make(map[string]bool)
+	for _, completed := range builder.completed[key] {
+		for _, children := range builder.children(completed) {
+			if id := packedID(completed.choice, children); !seen[id] {
+				seen[id] = true
+				node.Alternatives = append(node.Alternatives,
+					&PackedNode{completed.choice, children})
+			}
+		}
+	}
+	sort.SliceStable(node.Alternatives, func(i, j int) bool {
+		return node.Alternatives[i].Choice < node.Alternatives[j].Choice
+	})
+	return node
+}
+
+// Returns the distinct sequences of nodes matching the symbols before the dot.
+func (builder *forestBuilder) children(current *state) [][]*ForestNode {
+	if current.dot == 0 {
+		return [][]*ForestNode{nil}
+	}
+	if sequences, ok := builder.sequences[current]; ok {
+		return sequences
+	}
+	var sequences [][]*ForestNode
+	seen := make(map[string]bool)
+	for _, link := range current.links {
+		child := builder.child(current, link)
+		for _, prefix := range builder.children(link.prev) {
+			sequence := append(append(make([]*ForestNode, 0, current.dot), prefix...), child)
+			if id := packedID(0, sequence); !seen[id] {
+				seen[id] = true
+				sequences = append(sequences, sequence)
+			}
+		}
+	}
+	builder.sequences[current] = sequences
+	return sequences
+}
+
+// Returns the node matching the symbol that the link advanced the dot over.
+func (builder *forestBuilder) child(current *state, link link) *ForestNode {
+	symbol := builder.parser.symbols(current.item)[current.dot-1]
+	switch {
+	case link.token != nil:
+		key := span{fmt.Sprint(symbol), link.token.Start, link.token.End}
+		if node, ok := builder.terminals[key]; ok {
+			return node
+		}
+		node := &ForestNode{Start: link.token.Start, End: link.token.End,
+			Text: link.token.Text, Groups: link.token.Groups}
+		builder.terminals[key] = node
+		return node
+	case link.child != nil:
+		return builder.symbol(link.child.rule, link.child.origin, link.child.end)
+	}
+	return builder.symbol(symbol.(RuleMatcher).name, current.end, current.end)
+}
+
+// Identifies an alternative by its choice and the identity of its children.
+func packedID(choice int, children []*ForestNode) string {
+	var id strings.Builder
+	fmt.Fprint(&id, choice)
+	for _, child := range children {
+		fmt.Fprintf(&id, " %p", child)
+	}
+	return id.String()
+}
+
+// Returns the number of parse trees in the forest, or false if there are
+// infinitely many.  Counts larger than math.MaxInt are given as math.MaxInt.
+func (forest *Forest) Count() (int, bool) {
+	const counting = -1
+	counts := make(map[*ForestNode]int)
+	var count func(node *ForestNode) (int, bool)
+	count = func(node *ForestNode) (int, bool) {
+		if node.IsTerminal() {
+			return 1, true
+		}
+		if n, ok := counts[node]; ok {
+			return n, n != counting
+		}
+		counts[node] = counting
+		total := 0
+		for _, alternative := range node.Alternatives {
+			product := 1
+			for _, child := range alternative.Children {
+				n, finite := count(child)
+				if !finite {
+					return 0, false
+				}
+				if n > 0 && product > math.MaxInt/n {
+					product = math.MaxInt
+				} else {
+					product *= n
+				}
+			}
+			if total > math.MaxInt-product {
+				total = math.MaxInt
+			} else {
+				total += product
+			}
+		}
+		counts[node] = total
+		return total, true
+	}
+	return count(forest.Root)
+}
+
+// Returns up to limit of the forest's parse trees, each as a Result that can be
+// evaluated.  The trees are enumerated in the order of the rules' choices, so
+// the first tree prefers each rule's earlier choices, and they share the nodes
+// of their common subtrees.  Cycles are not followed, so a cyclic forest only
+// gives its trees where no rule derives itself over the same span.
+func (forest *Forest) Derivations(limit int) []Result {
+	enum := enumerator{
+		limit: limit,
+		path:  make(map[*ForestNode]bool),
+		trees: make(map[*ForestNode][]*Node),
+	}
+	trees, _ := enum.enumerate(forest.Root)
+	results := make([]Result, len(trees))
+	for i, tree := range trees {
+		results[i] = Result{forest.Input, tree, forest.rules}
+	}
+	return results
+}
+
+// Enumerates the trees of forest nodes, up to a limit for each node, which is
+// enough for the same number of trees at the root.  The trees of a node that is
+// on the current path are cut (and results depending on a cut not remembered).
+type enumerator struct {
+	limit int
+	path  map[*ForestNode]bool
+	trees map[*ForestNode][]*Node
+}
+
+func (enum enumerator) enumerate(node *ForestNode) ([]*Node, bool) {
+	if node.IsTerminal() {
+		return []*Node{{Start: node.Start, End: node.End,
+			Text: node.Text, Groups: node.Groups}}, false
+	}
+	if trees, ok := enum.trees[node]; ok {
+		return trees, false
+	}
+	if enum.path[node] {
+		return nil, true
+	}
+	enum.path[node] = true
+	defer delete(enum.path, node)
+
+	var trees []*Node
+	cut := false
+	for _, alternative := range node.Alternatives {
+		if len(trees) >= enum.limit {
+			break
+		}
+		children := make([][]*Node, len(alternative.Children))
+		for i, child := range alternative.Children {
+			var childCut bool
+			children[i], childCut = enum.enumerate(child)
+			cut = cut || childCut
+		}
+		trees = enum.product(trees, node, alternative.Choice, children)
+	}
+	if !cut {
+		enum.trees[node] = trees
+	}
+	return trees, cut
+}
+
+// Appends the trees of a choice, for each combination of its children's trees.
+func (enum enumerator) product(trees []*Node, node *ForestNode, choice int, children [][]*Node) []*Node {
+	for _, options := range children {
+		if len(options) == 0 {
+			return trees
+		}
+	}
+	indices := make([]int, len(children))
+	for len(trees) < enum.limit {
+		tree := &Node{Rule: node.Rule, Choice: choice, Start: node.Start, End: node.End,
+			Children: make([]*Node, len(children))}
+		for i, options := range children {
+			tree.Children[i] = options[indices[i]]
+		}
+		trees = append(trees, tree)
+
+		i := len(indices) - 1
+		for ; i >= 0; i-- {
+			if indices[i]++; indices[i] < len(children[i]) {
+				break
+			}
+			indices[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+	return trees
+}
+
+// An Ambiguity is a span of the input that a rule matched in more than one way.
+// The alternatives show the rule's choice with the text that each of its
+// symbols matched, so that the competing rules can be told apart, e.g. for a
+// rule `sum ::= sum "+" sum` over "1+2+3" they are `sum ::= (sum "1+2") "+"
+// (sum "3")` and `sum ::= (sum "1") "+" (sum "2+3")`.
+type Ambiguity struct {
+	Rule         string
+	Start        int
+	End          int
+	Line         int
+	Column       int
+	Text         string
+	Alternatives []string
+}
+
+// Satisfies the error interface, including the position in the message.
+func (amb Ambiguity) Error() string {
+	return fmt.Sprintf("%d:%d: %s is ambiguous over %q: %s", amb.Line, amb.Column,
+		amb.Rule, amb.Text, strings.Join(amb.Alternatives, " or "))
+}
+
+// Returns the ambiguities of the forest in the order of their spans, with an
+// ambiguity before any that are nested in it.  An unambiguous input has none.
+func (forest *Forest) Ambiguities() []Ambiguity {
+	var ambiguous []*ForestNode
+	visited := make(map[*ForestNode]bool)
+	var visit func(node *ForestNode)
+	visit = func(node *ForestNode) {
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		if len(node.Alternatives) > 1 {
+			ambiguous = append(ambiguous, node)
+		}
+		for _, alternative := range node.Alternatives {
+			for _, child := range alternative.Children {
+				visit(child)
+			}
+		}
+	}
+	visit(forest.Root)
+	sort.Slice(ambiguous, func(i, j int) bool {
+		a, b := ambiguous[i], ambiguous[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End > b.End
+		}
+		return a.Rule < b.Rule
+	})
+
+	ambiguities := make([]Ambiguity, len(ambiguous))
+	for i, node := range ambiguous {
+		line, column := position(forest.Input, node.Start)
+		ambiguities[i] = Ambiguity{
+			Rule:   node.Rule,
+			Start:  node.Start,
+			End:    node.End,
+			Line:   line,
+			Column: column,
+			Text:   forest.Input[node.Start:node.End],
+		}
+		for _, alternative := range node.Alternatives {
+			ambiguities[i].Alternatives = append(ambiguities[i].Alternatives,
+				forest.formatAlternative(node, alternative))
+		}
+	}
+	return ambiguities
+}
+
+// Formats an alternative as the choice, e.g. `sum ::= (sum "1") "+" (sum "2")`.
+func (forest *Forest) formatAlternative(node *ForestNode, alternative *PackedNode) string {
+	var text strings.Builder
+	text.WriteString(node.Rule + " ::=")
+	if len(alternative.Children) == 0 {
+		text.WriteString(" epsilon")
+	}
+	for _, child := range alternative.Children {
+		matched := forest.Input[child.Start:child.End]
+		if child.IsTerminal() {
+			fmt.Fprintf(&text, " %q", matched)
+		} else {
+			fmt.Fprintf(&text, " (%s %q)", child.Rule, matched)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/forest_test.go
+
+package parser
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+var (
+	// Sums without associativity, so that longer sums have more parses.
+	ambiguousSumGrammar = testGrammar(
+		RuleSpec{"sum", spec(s{"sum"}, l{"+"}, s{"sum"}), lproj(first, third)},
+		RuleSpec{"sum", spec(p{"[0-9]"}), all},
+	)
+	// Two rules that both match the empty string.
+	ambiguousEmptyGrammar = testGrammar(
+		RuleSpec{"top", spec(s{"a"}, l{";"}), all},
+		RuleSpec{"a", spec(s{"b"}), all},
+		RuleSpec{"a", spec(s{"c"}), all},
+		RuleSpec{"b", spec(), all},
+		RuleSpec{"c", spec(), all},
+	)
+	// A rule that derives itself.
+	cyclicGrammar = testGrammar(
+		RuleSpec{"a", spec(s{"a"}), all},
+		RuleSpec{"a", spec(l{"x"}), all},
+	)
+)
+
+func TestForest_Count(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar Grammar
+		input   string
+		count   int
+		finite  bool
+	}{
+		{"unambiguous", sumGrammar, "1+2+3", 1, true},
+		{"single number", ambiguousSumGrammar, "1", 1, true},
+		{"two ways", ambiguousSumGrammar, "1+2+3", 2, true},
+		{"five ways", ambiguousSumGrammar, "1+2+3+4", 5, true},
+		{"shared subtrees", ambiguousSumGrammar, "1+2+3+4+5+6+7+8+9", 1430, true},
+		{"nullable rules", nullableGrammar, ";xx", 1, true},
+		{"empty alternatives", ambiguousEmptyGrammar, ";", 2, true},
+		{"cycle", cyclicGrammar, "x", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forest, err := ParseForest(tt.grammar, tt.input)
+			if err != nil {
+				t.Fatalf("ParseForest() error = %v", err)
+			}
+			count, finite := forest.Count()
+			if count != tt.count || finite != tt.finite {
+				t.Errorf("Count() = %d, %v, want %d, %v", count, finite, tt.count, tt.finite)
+			}
+		})
+	}
+}
+
+func TestForest_Derivations(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar Grammar
+		input   string
+		limit   int
+		want    []string
+	}{
+		{"all", ambiguousSumGrammar, "1+2+3", 10, []string{
+			`[["1", "2"], "3"]`,
+			`["1", ["2", "3"]]`,
+		}},
+		{"limited", ambiguousSumGrammar, "1+2+3+4", 3, []string{
+			`[[["1", "2"], "3"], "4"]`,
+			`[["1", ["2", "3"]], "4"]`,
+			`[["1", "2"], ["3", "4"]]`,
+		}},
+		{"empty alternatives", ambiguousEmptyGrammar, ";", 10, []string{
+			`[[[]], ";"]`,
+			`[[[]], ";"]`,
+		}},
+		{"cycle not followed", cyclicGrammar, "x", 10, []string{`["x"]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forest, err := ParseForest(tt.grammar, tt.input)
+			if err != nil {
+				t.Fatalf("ParseForest() error = %v", err)
+			}
+			var got []string
+			for _, result := range forest.Derivations(tt.limit) {
+				value, err := result.Value()
+				if err != nil {
+					t.Fatalf("Value() error = %v", err)
+				}
+				got = append(got, formatValue(value))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Derivations()\n  got %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForest_Ambiguities(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar Grammar
+		input   string
+		want    []string
+	}{
+		{"unambiguous", sumGrammar, "1+2+3", nil},
+		{"nested", ambiguousSumGrammar, "1+2+3+4", []string{
+			`1:1: sum is ambiguous over "1+2+3+4": ` +
+				`sum ::= (sum "1+2+3") "+" (sum "4") or ` +
+				`sum ::= (sum "1+2") "+" (sum "3+4") or ` +
+				`sum ::= (sum "1") "+" (sum "2+3+4")`,
+			`1:1: sum is ambiguous over "1+2+3": ` +
+				`sum ::= (sum "1+2") "+" (sum "3") or sum ::= (sum "1") "+" (sum "2+3")`,
+			`1:3: sum is ambiguous over "2+3+4": ` +
+				`sum ::= (sum "2+3") "+" (sum "4") or sum ::= (sum "2") "+" (sum "3+4")`,
+		}},
+		{"competing rules", ambiguousEmptyGrammar, ";", []string{
+			`1:1: a is ambiguous over "": a ::= (b "") or a ::= (c "")`,
+		}},
+		{"cycle", cyclicGrammar, "x", []string{
+			`1:1: a is ambiguous over "x": a ::= (a "x") or a ::= "x"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forest, err := ParseForest(tt.grammar, tt.input)
+			if err != nil {
+				t.Fatalf("ParseForest() error = %v", err)
+			}
+			var got []string
+			for _, ambiguity := range forest.Ambiguities() {
+				got = append(got, ambiguity.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ambiguities()\n  got %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForest_Errors(t *testing.T) {
+	_, err := ParseForest(sumGrammar, "1+")
+	want := `1:3: unexpected end of input, expected /[0-9]+/`
+	if err == nil || err.Error() != want {
+		t.Errorf("ParseForest() error = %v, want %s", err, want)
+	}
+}
+
+func TestParseForest_EarleyBNFGroups(t *testing.T) {
+	// Spacing after a group is the separator, unless a Kleene operator follows.
+	inputs := []string{
+		"a ::= ( b ) *",
+		"a ::= ( b )*",
+		"a ::= ( b ) c",
+		"a ::= ( b )\n  | c",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			forest, err := ParseForest(EarleyBNFGrammar(), input)
+			if err != nil {
+				t.Fatalf("ParseForest() error = %v", err)
+			}
+			if ambiguities := forest.Ambiguities(); len(ambiguities) > 0 {
+				t.Errorf("Ambiguities() = %v", ambiguities)
+			}
+		})
+	}
+}
+
+func TestParseForest_EarleyBNFGrammarFile(t *testing.T) {
+	data, err := os.ReadFile("../../grammar/earleybnf.grammar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forest, err := ParseForest(EarleyBNFGrammar(), string(data))
+	if err != nil {
+		t.Fatalf("ParseForest() error = %v", err)
+	}
+	if ambiguities := forest.Ambiguities(); len(ambiguities) > 0 {
+		t.Errorf("Ambiguities() = %v", ambiguities)
+	}
+	result, err := Parse(EarleyBNFGrammar(), string(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	derivations := forest.Derivations(2)
+	if len(derivations) != 1 || derivations[0].Tree.String() != result.Tree.String() {
+		t.Errorf("Derivations() differ from the Parse() tree")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/format.go
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats the rule in the EarleyBNF notation, e.g. `sum ::= sum "+" num | num`.
+// Post-processing is shown after `=>` unless it is the default \0, a choice
+// without symbols is shown as `epsilon` and Nothing as `null`.
+func (rule EarleyRule) String() string {
+	return rule.name + " ::= " + formatChoices(rule.choices)
+}
+
+func (choice Choice) String() string {
+	var text strings.Builder
+	if len(choice.symbols) == 0 {
+		text.WriteString("epsilon")
+	}
+	for i, symbol := range choice.symbols {
+		if i > 0 {
+			text.WriteString(" ")
+		}
+		fmt.Fprint(&text, symbol)
+	}
+	if choice.arrange != nil && choice.arrange != PostProcessing(all) {
+		text.WriteString(" => " + formatPostProcessing(choice.arrange))
+	}
+	return text.String()
+}
+
+func formatChoices(choices []Choice) string {
+	formatted := make([]string, len(choices))
+	for i, choice := range choices {
+		formatted[i] = choice.String()
+	}
+	return strings.Join(formatted, " | ")
+}
+
+var wordPattern = regexp.MustCompile(`^[A-Z_a-z][A-Z_a-z0-9]*$`)
+
+func formatPostProcessing(arrange PostProcessing) string {
+	switch arrange := arrange.(type) {
+	case nil, Nothing:
+		return "null"
+	case StringProjection:
+		return strconv.Quote(arrange.value)
+	case BooleanProjection:
+		return strconv.FormatBool(arrange.value)
+	case ItemProjection, PropertyGetter, ElementGetter, LengthGetter:
+		return projectionPath(arrange)
+	case ExpandList:
+		return projectionPath(arrange.ItemProjection) + "..."
+	case ListProjection:
+		values := make([]string, len(arrange.values))
+		for i, value := range arrange.values {
+			values[i] = formatPostProcessing(value)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case RecordProjection:
+		if len(arrange.attrs) == 0 {
+			return arrange.name + "{}"
+		}
+		attrs := make([]string, len(arrange.attrs))
+		for i, attribute := range arrange.attrs {
+			switch attribute := attribute.(type) {
+			case KeyValue:
+				key := attribute.key
+				if !wordPattern.MatchString(key) {
+					key = strconv.Quote(key)
+				}
+				attrs[i] = key + ": " + formatPostProcessing(attribute.value)
+			case ExpandRecord:
+				attrs[i] = projectionPath(attribute.ItemProjection) + "..."
+			}
+		}
+		return arrange.name + "{ " + strings.Join(attrs, ", ") + " }"
+	}
+	return fmt.Sprintf("%T", arrange)
+}
@@ -1,40 +1,84 @@
 package parser
 
+import (
+	"fmt"
+	"strconv"
+)
+
 type GrammarSpec struct {
-	rules []RuleSpec
+	rules    []RuleSpec
+	keywords []string
 }
 
 type RuleSpec struct {
-	name string
-	choice Choice
+	name    string
+	symbols []EarleySymbol
 	arrange PostProcessing
 }
 
 func NewGrammar() Grammar {
-	return &grammar{[]EarleyRule{}, ""}
+	return &grammar{rules: []EarleyRule{}}
 }
 
 type Grammar interface {
+	// Adds a rule to the grammar.  The first rule added is the start rule, and a
+	// rule with the name of an existing rule adds to its choices.
 	AddRule(rule EarleyRule)
+
+	// Returns the name of the rule that a complete input is parsed as.
+	Start() string
+
+	// Returns the rules of the grammar, in the order they were first added.
+	Rules() []EarleyRule
+
+	// Declares a keyword, a word that is only matched by literals: the parser
+	// rejects a pattern's match when its text is a keyword, e.g. so that a
+	// pattern for names does not match the reserved word "role".
+	AddKeyword(image string)
+
+	// Returns the declared keywords, in the order they were first declared.
+	Keywords() []string
 }
 
 func (g *grammar) AddRule(rule EarleyRule) {
 	if len(g.rules) == 0 {
 		g.start = rule.name
 	}
+	for i := range g.rules {
+		if g.rules[i].name == rule.name {
+			g.rules[i].choices = append(g.rules[i].choices, rule.choices...)
+			return
+		}
+	}
 	g.rules = append(g.rules, rule)
 }
 
+func (g *grammar) Start() string { return g.start }
+
+func (g *grammar) Rules() []EarleyRule { return g.rules }
+
+func (g *grammar) AddKeyword(image string) {
+	for _, keyword := range g.keywords {
+		if keyword == image {
+			return
+		}
+	}
+	g.keywords = append(g.keywords, image)
+}
+
+func (g *grammar) Keywords() []string { return g.keywords }
+
 type grammar struct {
-	rules []EarleyRule
-	start string
+	rules    []EarleyRule
+	start    string
+	keywords []string
 }
 
 type EarleyRule struct {
 	name    string
 	choices []Choice
 }
-	
+
 type Choice struct {
 	symbols []EarleySymbol
 	arrange PostProcessing
@@ -46,7 +90,9 @@ type EarleySymbol interface {
 
 func (LiteralMatcher) isSymbol() {}
 func (PatternMatcher) isSymbol() {}
-func (RuleMatcher)    isSymbol() {}
+func (RuleMatcher) isSymbol()    {}
+func (KleeneMatcher) isSymbol()  {}
+func (GroupMatcher) isSymbol()   {}
 
 type LiteralMatcher struct {
 	image string
@@ -59,3 +105,22 @@ type PatternMatcher struct {
 type RuleMatcher struct {
 	name string
 }
+
+// A symbol made optional (`?`) or repeated zero or more (`*`) or one or more
+// (`+`) times.  Kleene matchers and groups are replaced by helper rules before
+// parsing, see Normalize().
+type KleeneMatcher struct {
+	symbol EarleySymbol
+	kleene string
+}
+
+// A parenthesised group of choices, matched as though it were a rule.
+type GroupMatcher struct {
+	choices []Choice
+}
+
+func (m LiteralMatcher) String() string { return strconv.Quote(m.image) }
+func (m PatternMatcher) String() string { return "/" + m.pattern + "/" }
+func (m RuleMatcher) String() string    { return m.name }
+func (m KleeneMatcher) String() string  { return fmt.Sprint(m.symbol) + m.kleene }
+func (m GroupMatcher) String() string   { return "( " + formatChoices(m.choices) + " )" }
@@ -0,0 +1,406 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/load.go
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/SymbolNotFound/ggdl/pkg/lexer"
+)
+
+// Reads a grammar in the EarleyBNF format (see grammar/earleybnf.grammar).  The
+// text is parsed with EarleyBNFGrammar() and the records it evaluates to are
+// converted into the rules and keywords of a Grammar, the first rule being its
+// start rule.
+func LoadGrammar(reader io.Reader) (Grammar, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Parse(EarleyBNFGrammar(), string(data))
+	if err != nil {
+		return nil, err
+	}
+	value, err := result.Value()
+	if err != nil {
+		return nil, err
+	}
+	productions, ok := value.(List)
+	if !ok {
+		return nil, fmt.Errorf("grammar is a %s, not a list of rules",
+			describeValue(value))
+	}
+
+	g := NewGrammar()
+	for _, production := range productions {
+		if record, ok := production.(*Record); ok && record.Name == "Keyword" {
+			image, err := stringAttr(record, "image")
+			if err != nil {
+				return nil, err
+			}
+			if image, err = unquote(image); err != nil {
+				return nil, err
+			}
+			g.AddKeyword(image)
+			continue
+		}
+		rule, err := earleyRule(production)
+		if err != nil {
+			return nil, err
+		}
+		g.AddRule(rule)
+	}
+	return Normalize(g), nil
+}
+
+// Converts an EarleyRule record.
+func earleyRule(value Value) (EarleyRule, error) {
+	record, err := asRecord(value, "EarleyRule")
+	if err != nil {
+		return EarleyRule{}, err
+	}
+	name, err := stringAttr(record, "name")
+	if err != nil {
+		return EarleyRule{}, err
+	}
+	choices, err := choicesAttr(record, "choices")
+	if err != nil {
+		return EarleyRule{}, fmt.Errorf("rule %s: %w", name, err)
+	}
+	return EarleyRule{name, choices}, nil
+}
+
+func choicesAttr(record *Record, key string) ([]Choice, error) {
+	values, err := listAttr(record, key)
+	if err != nil {
+		return nil, err
+	}
+	choices := make([]Choice, len(values))
+	for i, value := range values {
+		if choices[i], err = choice(value); err != nil {
+			return nil, err
+		}
+	}
+	return choices, nil
+}
+
+// Converts a Choice record.  Without an arrange the choice projects \0, and
+// without symbols (as for `epsilon`) it matches the empty string.
+func choice(value Value) (Choice, error) {
+	record, err := asRecord(value, "Choice")
+	if err != nil {
+		return Choice{}, err
+	}
+	values, err := listAttr(record, "symbols")
+	if err != nil {
+		return Choice{}, err
+	}
+	choice := Choice{arrange: all}
+	for _, value := range values {
+		symbol, err := symbol(value)
+		if err != nil {
+			return Choice{}, err
+		}
+		choice.symbols = append(choice.symbols, symbol)
+	}
+	if arrange, ok := record.Get("arrange"); ok && arrange != nil {
+		if choice.arrange, err = postProcessing(arrange); err != nil {
+			return Choice{}, err
+		}
+	}
+	return choice, nil
+}
+
+// Converts a matcher record.  Kleene operators and groups are kept as they are
+// written, until the grammar is normalized.
+func symbol(value Value) (EarleySymbol, error) {
+	record, err := asRecord(value, "")
+	if err != nil {
+		return nil, err
+	}
+	switch record.Name {
+	case "RuleMatcher":
+		name, err := stringAttr(record, "name")
+		return RuleMatcher{name}, err
+	case "LiteralMatcher":
+		image, err := stringAttr(record, "image")
+		if err != nil {
+			return nil, err
+		}
+		image, err = unquote(image)
+		return LiteralMatcher{image}, err
+	case "PatternMatcher":
+		pattern, err := stringAttr(record, "pattern")
+		if err != nil {
+			return nil, err
+		}
+		pattern, err = parsePattern(pattern)
+		return PatternMatcher{pattern}, err
+	case "Kleene":
+		value, _ := record.Get("symbol")
+		inner, err := symbol(value)
+		if err != nil {
+			return nil, err
+		}
+		return kleene(record, inner)
+	case "Group":
+		choices, err := choicesAttr(record, "choices")
+		if err != nil {
+			return nil, err
+		}
+		if kleeneOp, _ := record.Get("kleene"); kleeneOp == nil {
+			return GroupMatcher{choices}, nil
+		}
+		return kleene(record, GroupMatcher{choices})
+	}
+	return nil, fmt.Errorf("unknown matcher %s", record.Name)
+}
+
+func kleene(record *Record, inner EarleySymbol) (EarleySymbol, error) {
+	op, err := stringAttr(record, "kleene")
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "?", "*", "+":
+		return KleeneMatcher{inner, op}, nil
+	}
+	return nil, fmt.Errorf("unknown Kleene operator %q", op)
+}
+
+// Converts the record of a post-processing projection.
+func postProcessing(value Value) (PostProcessing, error) {
+	record, err := asRecord(value, "")
+	if err != nil {
+		return nil, err
+	}
+	switch record.Name {
+	case "ItemProjection":
+		ref, err := intAttr(record, "ref")
+		return ItemProjection{ref}, err
+	case "StringProjection":
+		text, err := stringAttr(record, "value")
+		if err != nil {
+			return nil, err
+		}
+		text, err = unquote(text)
+		return StringProjection{text}, err
+	case "BooleanProjection":
+		text, err := stringAttr(record, "value")
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s value %q is not a boolean", record.Name, text)
+		}
+		return BooleanProjection{value}, nil
+	case "Nothing":
+		return Nothing{}, nil
+	case "PropertyGetter":
+		from, err := postProcessingAttr(record, "from")
+		if err != nil {
+			return nil, err
+		}
+		name, err := stringAttr(record, "name")
+		return PropertyGetter{from, name}, err
+	case "LengthGetter":
+		from, err := postProcessingAttr(record, "from")
+		return LengthGetter{from}, err
+	case "ElementGetter":
+		from, err := postProcessingAttr(record, "from")
+		if err != nil {
+			return nil, err
+		}
+		index, err := intAttr(record, "index")
+		return ElementGetter{from, index}, err
+	case "ExpandList":
+		ref, err := intAttr(record, "ref")
+		return ExpandList{ItemProjection{ref}}, err
+	case "ListProjection":
+		return listProjection(record)
+	case "RecordProjection":
+		return recordProjection(record)
+	}
+	return nil, fmt.Errorf("unknown post-processing %s", record.Name)
+}
+
+func postProcessingAttr(record *Record, key string) (PostProcessing, error) {
+	value, ok := record.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s", record.Name, key)
+	}
+	return postProcessing(value)
+}
+
+func listProjection(record *Record) (PostProcessing, error) {
+	values, err := listAttr(record, "values")
+	if err != nil {
+		return nil, err
+	}
+	projection := ListProjection{}
+	for _, value := range values {
+		item, err := postProcessing(value)
+		if err != nil {
+			return nil, err
+		}
+		projection.values = append(projection.values, item)
+	}
+	return projection, nil
+}
+
+// Converts a RecordProjection record.  Without a name the record is anonymous.
+func recordProjection(record *Record) (PostProcessing, error) {
+	var name string
+	if _, ok := record.Get("name"); ok {
+		var err error
+		if name, err = stringAttr(record, "name"); err != nil {
+			return nil, err
+		}
+	}
+	values, err := listAttr(record, "attrs")
+	if err != nil {
+		return nil, err
+	}
+	projection := RecordProjection{name: name}
+	for _, value := range values {
+		attribute, err := asRecord(value, "")
+		if err != nil {
+			return nil, err
+		}
+		switch attribute.Name {
+		case "KeyValue":
+			key, err := stringAttr(attribute, "key")
+			if err != nil {
+				return nil, err
+			}
+			if key, err = unquote(key); err != nil {
+				return nil, err
+			}
+			value, err := postProcessingAttr(attribute, "value")
+			if err != nil {
+				return nil, err
+			}
+			projection.attrs = append(projection.attrs, KeyValue{key, value})
+		case "ExpandRecord":
+			ref, err := intAttr(attribute, "ref")
+			if err != nil {
+				return nil, err
+			}
+			projection.attrs = append(projection.attrs, ExpandRecord{ItemProjection{ref}})
+		default:
+			return nil, fmt.Errorf("unknown attribute %s in record %s",
+				attribute.Name, name)
+		}
+	}
+	return projection, nil
+}
+
+// Returns the value as a record, checking its name unless name is empty.
+func asRecord(value Value, name string) (*Record, error) {
+	record, ok := value.(*Record)
+	if !ok || (name != "" && record.Name != name) {
+		if name == "" {
+			name = "record"
+		}
+		return nil, fmt.Errorf("expected %s, found %s", name, describeValue(value))
+	}
+	return record, nil
+}
+
+func stringAttr(record *Record, key string) (string, error) {
+	value, _ := record.Get(key)
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s %s is a %s, not a string",
+			record.Name, key, describeValue(value))
+	}
+	return text, nil
+}
+
+func intAttr(record *Record, key string) (int, error) {
+	text, err := stringAttr(record, key)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s %q is not a number", record.Name, key, text)
+	}
+	return number, nil
+}
+
+// Returns a list attribute, which is empty if the record does not have it or it
+// is null, e.g. the symbols of `Choice{}`.
+func listAttr(record *Record, key string) (List, error) {
+	value, _ := record.Get(key)
+	if value == nil {
+		return nil, nil
+	}
+	list, ok := value.(List)
+	if !ok {
+		return nil, fmt.Errorf("%s %s is a %s, not a list",
+			record.Name, key, describeValue(value))
+	}
+	return list, nil
+}
+
+// Decodes the escape sequences of a STRING, those of a JSON string, as the
+// lexer decodes them (rejecting any escape that JSON does not have).
+func unquote(text string) (string, error) {
+	if !strings.Contains(text, `\`) {
+		return text, nil
+	}
+	value, err := lexer.Unquote(`"` + text + `"`)
+	if err != nil {
+		return "", fmt.Errorf("%s %q", err, text)
+	}
+	return value, nil
+}
+
+// Converts a PATTERN (e.g. /\s+/m) into the regular expression it denotes.  The
+// escaped slashes are unescaped and flags after the closing slash are applied
+// to the whole expression.  A character class (e.g. [a-z]) is used as it is.
+func parsePattern(text string) (string, error) {
+	if !strings.HasPrefix(text, "/") {
+		return text, nil
+	}
+	end := strings.LastIndex(text, "/")
+	if end == 0 {
+		return "", fmt.Errorf("unterminated pattern %s", text)
+	}
+	var body strings.Builder
+	for i := 1; i < end; i++ {
+		if text[i] == '\\' && i+1 < end {
+			if text[i+1] != '/' {
+				body.WriteByte('\\')
+			}
+			i++
+		}
+		body.WriteByte(text[i])
+	}
+	if flags := text[end+1:]; flags != "" {
+		if strings.Trim(flags, "imsU") != "" {
+			return "", fmt.Errorf("invalid pattern flags %q in %s", flags, text)
+		}
+		return "(?" + flags + ":" + body.String() + ")", nil
+	}
+	return body.String(), nil
+}
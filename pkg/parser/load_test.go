@@ -0,0 +1,317 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/load_test.go
+
+package parser
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadGrammarFile(t *testing.T, path string) Grammar {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	g, err := LoadGrammar(file)
+	if err != nil {
+		t.Fatalf("LoadGrammar(%s) error = %v", path, err)
+	}
+	return g
+}
+
+// Reports the first difference between the rules of two grammars.
+func compareGrammars(t *testing.T, got, want Grammar) {
+	t.Helper()
+	if got.Start() != want.Start() {
+		t.Errorf("Start() = %s, want %s", got.Start(), want.Start())
+	}
+	gotRules, wantRules := got.Rules(), want.Rules()
+	for i := 0; i < len(gotRules) && i < len(wantRules); i++ {
+		if !reflect.DeepEqual(gotRules[i], wantRules[i]) {
+			t.Fatalf("rule %d\n  got %#v\n want %#v", i, gotRules[i], wantRules[i])
+		}
+	}
+	if len(gotRules) != len(wantRules) {
+		t.Errorf("%d rules, want %d", len(gotRules), len(wantRules))
+	}
+	if !reflect.DeepEqual(got.Keywords(), want.Keywords()) {
+		t.Errorf("Keywords() = %q, want %q", got.Keywords(), want.Keywords())
+	}
+}
+
+func TestLoadGrammar_SelfHosting(t *testing.T) {
+	loaded := loadGrammarFile(t, "../../grammar/earleybnf.grammar")
+	compareGrammars(t, loaded, EarleyBNFGrammar())
+
+	// The loaded grammar can load itself again.
+	data, err := os.ReadFile("../../grammar/earleybnf.grammar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Parse(loaded, string(data))
+	if err != nil {
+		t.Fatalf("Parse() with loaded grammar error = %v", err)
+	}
+	value, err := result.Value()
+	if err != nil {
+		t.Fatalf("Value() with loaded grammar error = %v", err)
+	}
+	bootstrap, _ := Parse(EarleyBNFGrammar(), string(data))
+	want, _ := bootstrap.Value()
+	if formatValue(value) != formatValue(want) {
+		t.Errorf("Value() with loaded grammar differs from the bootstrap grammar")
+	}
+}
+
+func TestLoadGrammar(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		input   string
+		want    string
+	}{
+		{"default projection",
+			`pair ::= WORD "=" WORD
+			 WORD ::= /[a-z]+/`,
+			"a=b", `["a", "=", "b"]`},
+		{"references and records",
+			`pair ::= WORD "=" WORD => Pair{ key: \1, value: \3 }
+			 WORD ::= /[a-z]+/`,
+			"a=b", `Pair{key: "a", value: "b"}`},
+		{"pattern subgroup",
+			`quoted ::= /'([^']*)'/ => \1`,
+			"'text'", `"text"`},
+		{"pattern flags",
+			`lines ::= /^a$\s^b$/m`,
+			"a\nb", `"a\nb"`},
+		{"escaped slash in pattern",
+			`path ::= /[a-z]+(\/[a-z]+)*/`,
+			"a/b/c", `"a/b/c"`},
+		{"character class",
+			`digits ::= [0-9] => [\0] | digits [0-9] => [\1..., \2]`,
+			"123", `["1", "2", "3"]`},
+		{"escaped literals and strings",
+			`tab ::= "\t" "\\" "\u0041" => [\0..., "\"quoted\""]`,
+			"\t\\A", `["\t", "\\", "A", "\"quoted\""]`},
+		{"escaped surrogate pair",
+			`smile ::= "\uD83D\uDE00" => \1`,
+			"\U0001F600", "\"\U0001F600\""},
+		{"attributes without commas",
+			`pair ::= "x" "y" => Pair{
+			   key: \1
+			   value: \2 }`,
+			"xy", `Pair{key: "x", value: "y"}`},
+		{"quoted keys",
+			`pair ::= "x" => Pair{ "the key": \1, }`,
+			"x", `Pair{the key: "x"}`},
+		{"optional matcher",
+			`list ::= "[" ","? "]" => [\2]`,
+			"[]", `[null]`},
+		{"optional matcher present",
+			`list ::= "[" ","? "]" => [\2]`,
+			"[,]", `[","]`},
+		{"element and property getters",
+			`top ::= pair => [\1.1, \1.2.key]
+			 pair ::= "a" item => [\1, \2]
+			 item ::= "b" => Item{ key: \1 }`,
+			"ab", `["a", "b"]`},
+		{"repeated group",
+			`list ::= "(" WORD ( "," WORD => \2 )* ")" => [\2, \3...]
+			 WORD ::= /[a-z]+/`,
+			"(a,b,c)", `["a", "b", "c"]`},
+		{"spaced repeated group",
+			`list ::= WORD ( "," WORD => \2 ) * => [\1, \2...]
+			 WORD ::= /[a-z]+/`,
+			"a,b,c", `["a", "b", "c"]`},
+		{"group before an atom",
+			`pair ::= ( WORD ) "=" WORD => [\1, \3]
+			 WORD ::= /[a-z]+/`,
+			"a=b", `[["a"], "b"]`},
+		{"optional group",
+			`call ::= WORD [ "(" WORD ")" ] => Call{ name: \1, arg: \2 }
+			 WORD ::= /[a-z]+/`,
+			"f", `Call{name: "f", arg: null}`},
+		{"repeated rule names",
+			`a ::= "x"
+			 a ::= "y"`,
+			"y", `["y"]`},
+		{"epsilon",
+			`list ::= "(" items ")" => \2
+			 items ::= epsilon | items "x" => [\1..., \2]`,
+			"(xx)", `["x", "x"]`},
+		{"epsilon with projection",
+			`opt ::= "x" | epsilon => null`,
+			"", `null`},
+		{"null, booleans and length",
+			`top ::= "x" "y" => [null, true, false, len(\0)]`,
+			"xy", `[null, true, false, 2]`},
+		{"anonymous record",
+			`pair ::= WORD "=" WORD => { key: \1, value: \3, }
+			 WORD ::= /[a-z]+/`,
+			"a=b", `{key: "a", value: "b"}`},
+		{"empty list and records",
+			`top ::= "x" => [[], Empty{}, {}]`,
+			"x", `[[], Empty{}, {}]`},
+		{"pattern projected into a record",
+			`var ::= /[A-Z]([a-z]*)/ => Var{ name: \0, rest: \1 }`,
+			"Xyz", `Var{name: "Xyz", rest: "yz"}`},
+		{"keyword",
+			`stmt ::= "(" name ")" => \2
+			 name ::= /[a-z]+/
+			 keyword "role"`,
+			"(roles)", `"roles"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := LoadGrammar(strings.NewReader(tt.grammar))
+			if err != nil {
+				t.Fatalf("LoadGrammar() error = %v", err)
+			}
+			result, err := Parse(g, tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			value, err := result.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if got := formatValue(value); got != tt.want {
+				t.Errorf("Value()\n  got %s\n want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadGrammar_Keywords(t *testing.T) {
+	g := loadGrammarFile(t, "../../grammar/gdl_hrf.grammar")
+	want := []string{"role", "init", "base", "true", "next",
+		"legal", "does", "terminal", "goal", "distinct"}
+	if !reflect.DeepEqual(g.Keywords(), want) {
+		t.Errorf("Keywords() = %q, want %q", g.Keywords(), want)
+	}
+	if _, err := Parse(g, "role(white)"); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+	wantErr := `1:6: unexpected "r", expected /%+\s*(.*)/ or /(?m:\s+)/ or /[a-z][A-Z_a-z]*/`
+	if _, err := Parse(g, "role(role)"); err == nil || err.Error() != wantErr {
+		t.Errorf("Parse() error = %v, want %s", err, wantErr)
+	}
+}
+
+func TestLoadGrammar_OptionalHelpers(t *testing.T) {
+	g, err := LoadGrammar(strings.NewReader(
+		`a ::= b? c? | b? "x"
+		 b ::= "b" c?
+		 c ::= "c"`))
+	if err != nil {
+		t.Fatalf("LoadGrammar() error = %v", err)
+	}
+	var names []string
+	for _, rule := range g.Rules() {
+		names = append(names, rule.name)
+	}
+	want := []string{"a", "a$1", "a$2", "b", "b$1", "c"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("LoadGrammar() rules = %v, want %v", names, want)
+	}
+}
+
+func TestLoadGrammar_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		want    string
+	}{
+		{"syntax error", `a ::= "x" =>`,
+			`1:13: unexpected end of input, expected "[" or "\\" or "false" or ` +
+				`"len" or "null" or "true" or "{" or ` +
+				`/"((?:\\["bfnrt/\\]|\\u[a-fA-F0-9]{4}|[^"\\\n])*)"/ or ` +
+				`/(?m:\(\*([^*]+|\*+[^)])*\*+\))/ or /(?m:\s+)/ or /[A-Z_a-z][A-Z_a-z0-9]*/`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadGrammar(strings.NewReader(tt.grammar))
+			if err == nil {
+				t.Fatalf("LoadGrammar() succeeded, want error %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("LoadGrammar() error\n  got %s\n want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`[a-z]`, `[a-z]`},
+		{`/\s+/`, `\s+`},
+		{`/\s+/m`, `(?m:\s+)`},
+		{`/a\/b/`, `a/b`},
+		{`/a\\/`, `a\\`},
+		{`/\/(?:\\.|[^\\\n])+?\/m?/`, `/(?:\\.|[^\\\n])+?/m?`},
+	}
+	for _, tt := range tests {
+		got, err := parsePattern(tt.text)
+		if err != nil {
+			t.Errorf("parsePattern(%s) error = %v", tt.text, err)
+		} else if got != tt.want {
+			t.Errorf("parsePattern(%s) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+	for _, text := range []string{`/x/q`, `/x`} {
+		if got, err := parsePattern(text); err == nil {
+			t.Errorf("parsePattern(%s) = %s, want error", text, got)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`plain`, "plain"},
+		{`\t\\\"\/`, "\t\\\"/"},
+		{`\u0041\u00e9`, "A\u00e9"},
+		{`\uD83D\uDE00`, "\U0001F600"},
+		{`\uD83D\uDE00!`, "\U0001F600!"},
+		{`\uD83D`, "\uFFFD"},
+		{`\uD83Dx`, "\uFFFDx"},
+		{`\uD83D\u0041`, "\uFFFDA"},
+		{`\uDE00\uD83D`, "\uFFFD\uFFFD"},
+	}
+	for _, tt := range tests {
+		got, err := unquote(tt.text)
+		if err != nil {
+			t.Errorf("unquote(%s) error = %v", tt.text, err)
+		} else if got != tt.want {
+			t.Errorf("unquote(%s) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	for _, text := range []string{`\u12`, `\uXYZW`, `\uD83D\u12`, `\q`, `\'`, `a\`} {
+		if got, err := unquote(text); err == nil {
+			t.Errorf("unquote(%s) = %q, want error", text, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/normalize.go
+
+package parser
+
+import "fmt"
+
+// Returns an equivalent grammar where each Kleene matcher and group has been
+// replaced by a reference to a helper rule.  Helper rules are named after the
+// rule they are in and numbered in the order they are found, e.g. the first
+// helper for rule `_` is `_$1`, and they are added right after that rule.  A
+// number is skipped if the grammar already has a rule of that name (which could
+// only have been added through the Grammar interface, as `$` is not allowed in
+// the names of a grammar file).  The same construct used again within a rule
+// shares its helper.
+//
+// The helpers project values so that the enclosing rule sees each construct as
+// a single item:
+//
+//	x?      R$n ::= x => \1 | epsilon => null
+//	x*      R$n ::= R$n x => [\1..., \2] | epsilon => []
+//	x+      R$n ::= R$n x => [\1..., \2] | x => [\1]
+//	( .. )  R$n ::= the group's choices, with their own projections
+//
+// so an optional is null when absent and a repetition is a flat list of the
+// values of the repeated item.  Kleene operators on a group apply to the
+// group's helper, which becomes a separate helper from the repetition.
+func Normalize(g Grammar) Grammar {
+	normal := NewGrammar()
+	taken := make(map[string]bool)
+	for _, rule := range g.Rules() {
+		taken[rule.name] = true
+	}
+	for _, rule := range g.Rules() {
+		n := normalizer{rule: rule.name, taken: taken, helpers: make(map[string]string)}
+		choices := n.choices(rule.choices)
+		normal.AddRule(EarleyRule{rule.name, choices})
+		for _, helper := range n.pending {
+			normal.AddRule(helper)
+		}
+	}
+	for _, keyword := range g.Keywords() {
+		normal.AddKeyword(keyword)
+	}
+	return normal
+}
+
+// The helper rules being added for one of the grammar's rules.
+type normalizer struct {
+	rule  string
+	count int
+	// The names of the grammar's rules and of the helpers added so far.
+	taken   map[string]bool
+	helpers map[string]string
+	pending []EarleyRule
+}
+
+func (n *normalizer) choices(choices []Choice) []Choice {
+	normal := make([]Choice, len(choices))
+	for i, choice := range choices {
+		normal[i] = Choice{arrange: choice.arrange}
+		for _, symbol := range choice.symbols {
+			normal[i].symbols = append(normal[i].symbols, n.symbol(symbol))
+		}
+	}
+	return normal
+}
+
+func (n *normalizer) symbol(symbol EarleySymbol) EarleySymbol {
+	switch symbol := symbol.(type) {
+	case GroupMatcher:
+		return n.helper(symbol, func(name string) []Choice {
+			return n.choices(symbol.choices)
+		})
+	case KleeneMatcher:
+		return n.helper(symbol, func(name string) []Choice {
+			self, inner := RuleMatcher{name}, n.symbol(symbol.symbol)
+			switch symbol.kleene {
+			case "?":
+				return []Choice{
+					{spec(inner), first},
+					{spec(), Nothing{}},
+				}
+			case "*":
+				return []Choice{
+					{spec(self, inner), lproj(first_cat, second)},
+					{spec(), lproj()},
+				}
+			}
+			return []Choice{
+				{spec(self, inner), lproj(first_cat, second)},
+				{spec(inner), lproj(first)},
+			}
+		})
+	}
+	return symbol
+}
+
+// Returns a reference to the helper rule for the construct, adding the rule if
+// this is its first use in the rule being normalized.  The helper is numbered
+// before its choices are normalized, so nested helpers are numbered after it.
+func (n *normalizer) helper(construct EarleySymbol, choices func(name string) []Choice) RuleMatcher {
+	key := fmt.Sprintf("%#v", construct)
+	if name, ok := n.helpers[key]; ok {
+		return RuleMatcher{name}
+	}
+	var name string
+	for name == "" || n.taken[name] {
+		n.count++
+		name = fmt.Sprintf("%s$%d", n.rule, n.count)
+	}
+	n.taken[name] = true
+	n.helpers[key] = name
+	index := len(n.pending)
+	n.pending = append(n.pending, EarleyRule{name: name})
+	n.pending[index].choices = choices(name)
+	return RuleMatcher{name}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/normalize_test.go
+
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []RuleSpec
+		want  string
+	}{
+		{"optional",
+			[]RuleSpec{{"a", spec(KleeneMatcher{l{"x"}, "?"}, l{"y"}), all}},
+			`a ::= a$1 "y"
+a$1 ::= "x" => \1 | epsilon => null`},
+		{"zero or more",
+			[]RuleSpec{{"a", spec(KleeneMatcher{s{"b"}, "*"}), first}},
+			`a ::= a$1 => \1
+a$1 ::= a$1 b => [\1..., \2] | epsilon => []`},
+		{"one or more",
+			[]RuleSpec{{"a", spec(KleeneMatcher{p{"[0-9]"}, "+"}), first}},
+			`a ::= a$1 => \1
+a$1 ::= a$1 /[0-9]/ => [\1..., \2] | /[0-9]/ => [\1]`},
+		{"group",
+			[]RuleSpec{{"a", spec(l{"<"}, GroupMatcher{[]Choice{
+				{spec(l{"x"}), first}, {spec(l{"y"}, l{"z"}), all}}}), second}},
+			`a ::= "<" a$1 => \2
+a$1 ::= "x" => \1 | "y" "z"`},
+		{"repeated group",
+			[]RuleSpec{{"a", spec(KleeneMatcher{GroupMatcher{[]Choice{
+				{spec(l{","}, s{"b"}), second}}}, "*"}), all}},
+			`a ::= a$1
+a$1 ::= a$1 a$2 => [\1..., \2] | epsilon => []
+a$2 ::= "," b => \2`},
+		{"nested groups",
+			[]RuleSpec{{"a", spec(GroupMatcher{[]Choice{
+				{spec(KleeneMatcher{l{"x"}, "?"}, GroupMatcher{[]Choice{{spec(l{"y"}), all}}}), all}}}),
+				all}},
+			`a ::= a$1
+a$1 ::= a$2 a$3
+a$2 ::= "x" => \1 | epsilon => null
+a$3 ::= "y"`},
+		{"shared helpers",
+			[]RuleSpec{
+				{"a", spec(KleeneMatcher{s{"b"}, "?"}, l{"x"}), all},
+				{"a", spec(KleeneMatcher{s{"b"}, "?"}, KleeneMatcher{s{"b"}, "+"}), all},
+				{"b", spec(KleeneMatcher{s{"b"}, "?"}), all},
+			},
+			`a ::= a$1 "x" | a$1 a$2
+a$1 ::= b => \1 | epsilon => null
+a$2 ::= a$2 b => [\1..., \2] | b => [\1]
+b ::= b$1
+b$1 ::= b => \1 | epsilon => null`},
+		{"name in use",
+			[]RuleSpec{
+				{"a", spec(KleeneMatcher{l{"x"}, "?"}, s{"a$1"}), all},
+				{"a$1", spec(l{"y"}), all},
+			},
+			`a ::= a$2 a$1
+a$2 ::= "x" => \1 | epsilon => null
+a$1 ::= "y"`},
+		{"already normal",
+			[]RuleSpec{{"a", spec(s{"b"}), all}, {"b", spec(), Nothing{}}},
+			`a ::= b
+b ::= epsilon => null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normal := Normalize(testGrammar(tt.rules...))
+			lines := []string{}
+			for _, rule := range normal.Rules() {
+				lines = append(lines, rule.String())
+			}
+			if got := strings.Join(lines, "\n"); got != tt.want {
+				t.Errorf("Normalize() rules\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize_Values(t *testing.T) {
+	tests := []struct {
+		name   string
+		kleene string
+		input  string
+		want   string
+	}{
+		{"optional absent", "?", "", `null`},
+		{"optional present", "?", "x", `"x"`},
+		{"zero or more, none", "*", "", `[]`},
+		{"zero or more", "*", "xxx", `["x", "x", "x"]`},
+		{"one or more", "+", "x", `["x"]`},
+		{"one or more, several", "+", "xx", `["x", "x"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrammar(RuleSpec{"a", spec(KleeneMatcher{l{"x"}, tt.kleene}), first})
+			result, err := Parse(g, tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			value, err := result.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if got := formatValue(value); got != tt.want {
+				t.Errorf("Value() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,12 +4,15 @@ type PostProcessing interface {
 	isPostProc()
 }
 
-func (Nothing) isPostProc()          {}
-func (StringProjection) isPostProc() {}
-func (ItemProjection) isPostProc()   {}
-func (ListProjection) isPostProc()   {}
-func (RecordProjection) isPostProc() {}
-func (PropertyGetter) isPostProc()   {}
+func (Nothing) isPostProc()           {}
+func (StringProjection) isPostProc()  {}
+func (ItemProjection) isPostProc()    {}
+func (ListProjection) isPostProc()    {}
+func (RecordProjection) isPostProc()  {}
+func (PropertyGetter) isPostProc()    {}
+func (ElementGetter) isPostProc()     {}
+func (LengthGetter) isPostProc()      {}
+func (BooleanProjection) isPostProc() {}
 
 type Nothing struct{}
 
@@ -17,6 +20,10 @@ type StringProjection struct {
 	value string
 }
 
+type BooleanProjection struct {
+	value bool
+}
+
 type ItemProjection struct {
 	ref int
 }
@@ -29,8 +36,9 @@ type ExpandList struct {
 	ItemProjection
 }
 
+// Gets an element of a list, from a reference or another getter.
 type ElementGetter struct {
-	ItemProjection
+	from  PostProcessing
 	index int
 }
 
@@ -55,7 +63,13 @@ type ExpandRecord struct {
 	ItemProjection
 }
 
+// Gets an attribute of a record, from a reference or another getter.
 type PropertyGetter struct {
-	ItemProjection
+	from PostProcessing
 	name string
 }
+
+// Gets the number of elements of a list, from a reference or a getter.
+type LengthGetter struct {
+	from PostProcessing
+}
@@ -6,6 +6,7 @@ type s = RuleMatcher
 type kv = KeyValue
 type str = StringProjection
 type ref = ItemProjection
+type k = KleeneMatcher
 type get = PropertyGetter
 
 // Variable-length symbol list constructor.
@@ -35,80 +36,89 @@ var second = ref{2}
 var third = ref{3}
 var fourth = ref{4}
 var fifth = ref{5}
+var sixth = ref{6}
+var seventh = ref{7}
 var first_cat = ExpandList{first}
 
-// A lightly-commented already-compiled 
+// A lightly-commented already-compiled transcription of the EarleyBNF grammar
+// in grammar/earleybnf.grammar, which LoadGrammar uses to read grammar files.
+// Rules are in the same order as in the file, and the grammar is normalized as
+// LoadGrammar would normalize it, so the optional `?` matchers become helper
+// rules (named rule$N) following the rule they are in.
 func EarleyBNFGrammar() Grammar {
-	gs := GrammarSpec{ rules: []EarleyRule{
+	gs := GrammarSpec{rules: []RuleSpec{
 		// Starting state, a grammar is a sequence of spacing-delimited productions.
-		{"input", spec(s{"_"}, s{"grammar"}, s{"_"}), rproj(
-			"GrammarSpec", kv{"rules", second})},
-		{"grammar", def(s{"production"}), all},
-		{"grammar", def(s{"grammar"}, s{"__"}, s{"production"}),
-			// Also captures top-level comments from __.
-			lproj(first_cat, ExpandList{second}, third)},
+		{"input", spec(s{"_"}, s{"grammar"}, s{"_"}), second},
+		{"grammar", spec(s{"production"}), all},
+		{"grammar", spec(s{"grammar"}, s{"_"}, s{"production"}),
+			lproj(first_cat, third)},
 
 		// Spacing, optional `_` and at-least-one `__`.  Comments are captured here
 		// but will be skipped unless the enclosing rule maintains a reference.
-		{"_", def(s{"__$1"}), all},
-		{"__$1", def(s{"__"}), first},
-		{"__$1", def(), Nothing{}},
-		{"__", spec(s{"__$1"}, s{"SPACING"}), lproj(ExpandList{first})},
-		{"__", spec(s{"__$1"}, s{"COMMENT"}),
-				lproj(first_cat, rproj("Comment", kv{"text", second}))},
-
-		// Patterns may have post-processing defined, but only simple references.
+		{"_", spec(k{s{"__"}, "?"}), all},
+		{"__", spec(k{s{"__"}, "?"}, s{"SPACING"}), lproj(first_cat)},
+		{"__", spec(k{s{"__"}, "?"}, s{"COMMENT"}),
+			lproj(first_cat, rproj("Comment", kv{"text", second}))},
+		{"SPACING", spec(p{`(?m:\s+)`}), all},
+		{"COMMENT", spec(p{`(?m:\(\*([^*]+|\*+[^)])*\*+\))`}), all},
+
+		// Rules and patterns are both productions of an EarleyRule.
+		{"production",
+			spec(s{"WORD"}, s{"_"}, l{"::="}, s{"_"}, s{"rule_body"}),
+			rproj("EarleyRule", kv{"name", first}, kv{"choices", fifth})},
 		{"production",
 			spec(s{"WORD"}, s{"_"}, l{"::="}, s{"_"}, s{"pattern_body"}),
-			rproj("EarleyRule", kv{"name", first},
-			  kv{"choices", lproj(fifth)})},
-		{"pattern_body",
-		  spec(s{"PATTERN"}),
-			rproj("Choice",
-				kv{"symbols", lproj(rproj("PatternMatcher", kv{"pattern", first}))},
-				kv{"arrange", all})},
+			rproj("EarleyRule", kv{"name", first}, kv{"choices", lproj(fifth)})},
+		// Keywords are declared alongside the rules.
+		{"production", spec(l{"keyword"}, s{"_"}, s{"STRING"}),
+			rproj("Keyword", kv{"image", third})},
+
+		// Patterns may have post-processing defined, projecting from their groups.
+		{"pattern_body", spec(s{"PATTERN"}),
+			rproj("Choice", kv{"symbols",
+				lproj(rproj("PatternMatcher", kv{"pattern", first}))})},
 		{"pattern_body",
-		  spec(s{"PATTERN"}, s{"_"}, l{"=>"}, s{"_"}, s{"postproc_ref"}),
+			spec(s{"PATTERN"}, s{"_"}, l{"=>"}, s{"_"}, s{"postproc_atom"}),
 			rproj("Choice",
 				kv{"symbols", lproj(rproj("PatternMatcher", kv{"pattern", first}))},
 				kv{"arrange", fifth})},
+		{"PATTERN", spec(p{`/(?:\\.|[^\\\n])+?/m?`}), all},
 
 		// Rules may be repeated, or rules may have their alternate choices listed.
-		{"production",
-			spec(s{"WORD"}, s{"_"}, l{"::="}, s{"_"}, s{"rule_body"}),
-			rproj("EarleyRule", kv{"name", first}, kv{"choices", fifth})},
 		{"rule_body", spec(s{"parse_choice"}), all},
 		{"rule_body",
 			spec(s{"rule_body"}, s{"_"}, l{"|"}, s{"_"}, s{"parse_choice"}),
 			lproj(first_cat, fifth)},
 
 		// Each choice has its own post-production context.
+		{"parse_choice", spec(s{"rule_expr"}),
+			rproj("Choice", kv{"symbols", first})},
 		{"parse_choice",
 			spec(s{"rule_expr"}, s{"_"}, l{"=>"}, s{"_"}, s{"postproc_atom"}),
-			rproj("Choice", kv{"tokens", first}, kv{"arrange", fifth})},
-		// By default, all Earley state matches are projected.
-		{"parse_choice", spec(s{"rule_expr"}),
-			rproj("Choice", kv{"tokens", first}, kv{"arrange", all})},
+			rproj("Choice", kv{"symbols", first}, kv{"arrange", fifth})},
+		{"parse_choice", spec(l{"epsilon"}), rproj("Choice")},
+		{"parse_choice",
+			spec(l{"epsilon"}, s{"_"}, l{"=>"}, s{"_"}, s{"postproc_atom"}),
+			rproj("Choice", kv{"arrange", fifth})},
 
 		// Each rule expression is a simple concatenation of rule_atom members.
 		{"rule_expr", spec(s{"rule_atom"}), all},
-		{"rule_expr",
-			spec(s{"rule_expr"}, s{"_"}, s{"rule_atom"}),
+		{"rule_expr", spec(s{"rule_expr"}, s{"_"}, s{"rule_atom"}),
 			lproj(first_cat, third)},
 
 		// Rule atoms are matchers or subexpressions (also composed of matchers).
 		{"rule_atom", spec(s{"rule_matcher"}), first},
-		{"rule_atom", spec(s{"rule_matcher"}, s{"KLEENE_MOD"}),
-			rproj("record", ExpandRecord{first}, kv{"kleene", second})},
+		{"rule_atom", spec(s{"rule_matcher"}, s{"kleene_mod"}),
+			rproj("Kleene", kv{"symbol", first}, kv{"kleene", second})},
 		{"rule_atom", spec(l{"("}, s{"_"}, s{"rule_body"}, s{"_"}, l{")"}),
-			rproj("Expr", kv{"tokens", third})},
-		{"rule_atom", spec(l{"("}, s{"_"}, s{"rule_body"}, s{"_"}, l{")"},
-			s{"_"}, s{"kleene_mod"}),
-			rproj("Expr", kv{"tokens", third}, kv{"kleene", ref{7}})},
+			rproj("Group", kv{"choices", third})},
+		{"rule_atom",
+			spec(l{"("}, s{"_"}, s{"rule_body"}, s{"_"}, l{")"}, s{"_"}, s{"kleene_mod"}),
+			rproj("Group", kv{"choices", third}, kv{"kleene", seventh})},
 		{"rule_atom", spec(l{"["}, s{"_"}, s{"rule_body"}, s{"_"}, l{"]"}),
-			rproj("Expr", kv{"tokens", third}, kv{"kleene", str{"?"}})},
+			rproj("Group", kv{"choices", third}, kv{"kleene", str{"?"}})},
 		{"rule_atom", spec(l{"{"}, s{"_"}, s{"rule_body"}, s{"_"}, l{"}"}),
-			rproj("Expr", kv{"tokens", third}, kv{"kleene", str{"*"}})},
+			rproj("Group", kv{"choices", third}, kv{"kleene", str{"*"}})},
 
 		// Symbolic references, literal strings or character classes (e.g., [a-z])
 		{"rule_matcher", spec(s{"WORD"}),
@@ -117,90 +127,113 @@ func EarleyBNFGrammar() Grammar {
 			rproj("LiteralMatcher", kv{"image", first})},
 		{"rule_matcher", spec(s{"CHARCLASS"}),
 			rproj("PatternMatcher", kv{"pattern", first})},
+		{"kleene_mod", spec(p{"[?*+]"}), all},
 
 		// Non-trivial token definitions.
-		{"SPACING", spec(p{"(?m:\\s+)"}), all},
-		{"COMMENT", spec(p{"(?m:\\(\\*([^*]+|\\*+[^)])*\\*+\\))"}), all},
 		{"WORD", spec(p{"[A-Z_a-z][A-Z_a-z0-9]*"}), all},
-		{"NUMBER", spec(p{"0|[1-9][0-9]*"}), all},
 		{"STRING",
-			spec(p{"\"(\\\\[\"bfnrt\\/\\\\]|\\u[a-fA-F0-9]{4}|[^\"\\\\\\n])*\""}),
+			spec(p{`"((?:\\["bfnrt/\\]|\\u[a-fA-F0-9]{4}|[^"\\\n])*)"`}),
 			first},
-		{"KLEENE_MOD", spec(p{"[?*+]"}), all},
 		// Only the simple `[` ... `]` form of character class is supported.
-		{"CHARCLASS", spec(p{"\\[(?:\\\\.|[^\\\\\\n])+?\\]"}), all},
-		// For more complicated (regular) expressions, use pattern notation.
-		{"PATTERN", spec(p{"/(?:\\\\.|[^\\\\\\n])+?/m?"}), all},
+		{"CHARCLASS", spec(p{`\[(?:\\.|[^\\\n])+?\]`}), all},
 
 		// Post-processing top-level constructions.
 		{"postproc_atom", spec(s{"postproc_prop"}), first},
 		{"postproc_atom", spec(s{"postproc_ref"}), first},
 		{"postproc_atom", spec(s{"postproc_list"}), first},
 		{"postproc_atom", spec(s{"postproc_record"}), first},
+		{"postproc_atom", spec(s{"STRING"}),
+			rproj("StringProjection", kv{"value", first})},
+		{"postproc_atom", spec(l{"null"}), rproj("Nothing")},
+		{"postproc_atom", spec(l{"true"}),
+			rproj("BooleanProjection", kv{"value", first})},
+		{"postproc_atom", spec(l{"false"}),
+			rproj("BooleanProjection", kv{"value", first})},
+		{"postproc_atom", spec(s{"postproc_len"}), first},
 
 		// (state reference)
 		{"postproc_ref", spec(l{"\\"}, s{"NUMBER"}),
 			rproj("ItemProjection", kv{"ref", second})},
+		{"NUMBER", spec(p{"0|[1-9][0-9]*"}), all},
 
 		// (property accessor)
 		{"postproc_prop", spec(s{"postproc_ref"}, l{"."}, s{"WORD"}),
-			rproj("PropertyGetter", kv{"ref", first}, kv{"name", third})},
+			rproj("PropertyGetter", kv{"from", first}, kv{"name", third})},
 		{"postproc_prop", spec(s{"postproc_prop"}, l{"."}, s{"WORD"}),
-			rproj("PropertyGetter", kv{"ref", first}, kv{"name", third})},
+			rproj("PropertyGetter", kv{"from", first}, kv{"name", third})},
 		{"postproc_prop", spec(s{"postproc_ref"}, l{"."}, s{"NUMBER"}),
-			rproj("ElementGetter", kv{"ref", first}, kv{"incex", third})},
+			rproj("ElementGetter", kv{"from", first}, kv{"index", third})},
 		{"postproc_prop", spec(s{"postproc_prop"}, l{"."}, s{"NUMBER"}),
-			rproj("ElementGetter", kv{"ref", first}, kv{"index", third})},
+			rproj("ElementGetter", kv{"from", first}, kv{"index", third})},
+
+		// (list length)
+		{"postproc_len",
+			spec(l{"len"}, s{"_"}, l{"("}, s{"_"}, s{"postproc_ref"}, s{"_"}, l{")"}),
+			rproj("LengthGetter", kv{"from", fifth})},
+		{"postproc_len",
+			spec(l{"len"}, s{"_"}, l{"("}, s{"_"}, s{"postproc_prop"}, s{"_"}, l{")"}),
+			rproj("LengthGetter", kv{"from", fifth})},
 
 		// (list projection)
 		{"postproc_list",
-		  spec(l{"["}, s{"_"}, s{"postproc_items"}, s{"_"}, l{","}, s{"_"}, l{"]"}),
+			spec(l{"["}, s{"_"}, s{"postproc_items"}, s{"_"}, l{"]"}),
 			rproj("ListProjection", kv{"values", third})},
 		{"postproc_list",
-		  spec(l{"["}, s{"_"}, s{"postproc_items"}, s{"_"}, l{"]"}),
+			spec(l{"["}, s{"_"}, s{"postproc_items"}, s{"_"}, l{","}, s{"_"}, l{"]"}),
 			rproj("ListProjection", kv{"values", third})},
+		{"postproc_list", spec(l{"["}, s{"_"}, l{"]"}), rproj("ListProjection")},
 
 		// (list items)
 		{"postproc_items", spec(s{"postproc_item"}), all},
 		{"postproc_items",
 			spec(s{"postproc_items"}, s{"_"}, l{","}, s{"_"}, s{"postproc_item"}),
 			lproj(first_cat, fifth)},
-
-		{"postproc_item", spec(s{"postproc_prop"}), first},
-		{"postproc_item", spec(s{"postproc_list"}), first},
-		{"postproc_item", spec(s{"postproc_record"}), first},
+		{"postproc_item", spec(s{"postproc_atom"}), first},
 		{"postproc_item", spec(s{"postproc_ref"}, l{"..."}),
 			rproj("ExpandList", kv{"ref", get{first, "ref"}})},
-		{"postproc_item", spec(s{"postproc_ref"}), first},
 
 		// (record projection)
+		{"postproc_record",
+			spec(s{"WORD"}, l{"{"}, s{"_"}, s{"postproc_keyvals"}, s{"_"}, l{"}"}),
+			rproj("RecordProjection", kv{"name", first}, kv{"attrs", fourth})},
 		{"postproc_record",
 			spec(s{"WORD"}, l{"{"}, s{"_"}, s{"postproc_keyvals"},
 				s{"_"}, l{","}, s{"_"}, l{"}"}),
 			rproj("RecordProjection", kv{"name", first}, kv{"attrs", fourth})},
+		{"postproc_record", spec(s{"WORD"}, l{"{"}, s{"_"}, l{"}"}),
+			rproj("RecordProjection", kv{"name", first})},
 		{"postproc_record",
-			spec(s{"WORD"}, l{"{"}, s{"_"}, s{"postproc_keyvals"}, s{"_"}, l{"}"}),
-			rproj("RecordProjection", kv{"name", first}, kv{"attrs", fourth})},
+			spec(l{"{"}, s{"_"}, s{"postproc_keyvals"}, s{"_"}, l{"}"}),
+			rproj("RecordProjection", kv{"attrs", third})},
+		{"postproc_record",
+			spec(l{"{"}, s{"_"}, s{"postproc_keyvals"}, s{"_"}, l{","}, s{"_"}, l{"}"}),
+			rproj("RecordProjection", kv{"attrs", third})},
+		{"postproc_record", spec(l{"{"}, s{"_"}, l{"}"}), rproj("RecordProjection")},
 
 		// (key-value attributes)
 		{"postproc_keyvals", spec(s{"postproc_kv"}), all},
 		{"postproc_keyvals",
 			spec(s{"postproc_keyvals"}, s{"_"}, l{","}, s{"_"}, s{"postproc_kv"}),
 			lproj(first_cat, fifth)},
+		{"postproc_keyvals", spec(s{"postproc_keyvals"}, s{"__"}, s{"postproc_kv"}),
+			lproj(first_cat, third)},
 		{"postproc_kv",
-		  spec(s{"kv_key"}, s{"_"}, l{":"}, s{"_"}, s{"kv_value"}),
+			spec(s{"kv_key"}, s{"_"}, l{":"}, s{"_"}, s{"postproc_atom"}),
 			rproj("KeyValue", kv{"key", first}, kv{"value", fifth})},
 		{"postproc_kv", spec(s{"postproc_ref"}, l{"..."}),
 			rproj("ExpandRecord", kv{"ref", get{first, "ref"}})},
 		{"kv_key", spec(s{"WORD"}), first},
 		{"kv_key", spec(s{"STRING"}), first},
-		{"kv_value", spec(s{"STRING"}), first},
-		{"kv_value", spec(s{"postproc_atom"}), first},
 	}}
+	// Keywords, so that `epsilon` is never taken to be a reference to a rule.
+	gs.keywords = []string{"epsilon"}
 
 	var g grammar
 	for _, rule := range gs.rules {
-		g.AddRule(rule)
+		g.AddRule(EarleyRule{rule.name, []Choice{{rule.symbols, rule.arrange}}})
+	}
+	for _, keyword := range gs.keywords {
+		g.AddKeyword(keyword)
 	}
-	return &g
+	return Normalize(&g)
 }
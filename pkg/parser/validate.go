@@ -0,0 +1,457 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/validate.go
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SymbolNotFound/ggdl/pkg/lexer"
+)
+
+// A problem that Validate() found in a grammar.  Rules are not positioned in
+// the text they were loaded from, so a diagnostic names the rule (which may be
+// a helper added by Normalize) and, for a problem in one of the rule's choices,
+// the index of that choice.  Severities are shared with the lexer's diagnostics,
+// a warning being a likely mistake that does not prevent parsing.
+type Diagnostic struct {
+	Rule string
+	// The index of the choice within the rule's choices, or -1 when it is the
+	// rule as a whole.  Choices are numbered from 1 in the message.
+	Choice   int
+	Severity lexer.Severity
+	// Which of the checks failed, one of the CODE_* constants below.  The message
+	// describes the particular problem, e.g. which rule is undefined.
+	Code    string
+	Message string
+}
+
+// Formats as `rule: message`, or as `rule, choice N: message` when the problem
+// is in one of the rule's choices, so that a diagnostic can be returned as an
+// error by tools that load and check a grammar.
+func (diag Diagnostic) Error() string {
+	if diag.Choice < 0 {
+		return fmt.Sprintf("%s: %s", diag.Rule, diag.Message)
+	}
+	return fmt.Sprintf("%s, choice %d: %s", diag.Rule, diag.Choice+1, diag.Message)
+}
+
+// Validate() reports each kind of problem with one of these codes.  Only the
+// unreachable rules, kind conflicts and the record conflicts between a rule's
+// alternatives are warnings, the rest are errors.
+const (
+	CODE_NO_RULES          = "no-rules"
+	CODE_UNDEFINED_RULE    = "undefined-rule"
+	CODE_UNREACHABLE_RULE  = "unreachable-rule"
+	CODE_UNPRODUCTIVE_RULE = "unproductive-rule"
+	CODE_INVALID_PATTERN   = "invalid-pattern"
+	CODE_REFERENCE_RANGE   = "reference-range"
+	CODE_EXPAND_NON_LIST   = "expand-non-list"
+	CODE_EXPAND_NON_RECORD = "expand-non-record"
+	CODE_RECORD_CONFLICT   = "record-conflict"
+	CODE_KIND_CONFLICT     = "kind-conflict"
+)
+
+// Checks the grammar for problems that would make parsing fail or evaluating
+// its projections fail, returning them in the order of the grammar's rules.
+// The grammar is normalized first, so the helpers of Kleene operators and
+// groups are checked (and reported) as rules of their own.
+//
+// Errors are reported for references to undefined rules, rules that cannot
+// match any input, invalid patterns, references beyond the items of a choice,
+// and for expansions (`\N...`) of values that are not a list, or not a record
+// of the same name as the (named) record they are expanded into.  Whether a
+// value is a string, list or record is inferred from the projections of the
+// rules that produce it, so an expansion is reported when any of the rule's
+// alternatives produces a value that does not fit.  Warnings are reported for
+// rules that are never used by the start rule, for rules whose alternatives
+// produce different kinds of values (e.g. a string and a list) and for rules
+// whose alternatives build records of different names (e.g. Foo{} and Bar{}),
+// which may be intended when the names tell the alternatives apart.
+func Validate(g Grammar) []Diagnostic {
+	g = Normalize(g)
+	if g.Start() == "" {
+		return []Diagnostic{{Choice: -1, Code: CODE_NO_RULES,
+			Message: "grammar has no rules"}}
+	}
+	v := validator{
+		rules:    make(map[string][]Choice),
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	for _, rule := range g.Rules() {
+		v.rules[rule.name] = append(v.rules[rule.name], rule.choices...)
+	}
+	v.inferTypes(g.Rules())
+	reachable := reachableRules(g.Start(), v.rules)
+	productive := productiveRules(g.Rules())
+
+	for _, rule := range g.Rules() {
+		for i, choice := range rule.choices {
+			v.checkSymbols(rule.name, i, choice)
+			v.checkReferences(rule.name, i, choice)
+			v.checkExpansions(rule.name, i, choice, choice.arrange)
+		}
+		if !reachable[rule.name] {
+			v.report(rule.name, -1, lexer.SEVERITY_WARNING, CODE_UNREACHABLE_RULE,
+				"rule is not reachable from start rule %s", g.Start())
+		}
+		if !productive[rule.name] {
+			v.report(rule.name, -1, lexer.SEVERITY_ERROR, CODE_UNPRODUCTIVE_RULE,
+				"rule cannot match any input")
+		}
+		if kinds := v.types[rule.name].kinds &^ (kindNull | kindAny); kinds&(kinds-1) != 0 {
+			v.report(rule.name, -1, lexer.SEVERITY_WARNING, CODE_KIND_CONFLICT,
+				"alternatives produce different kinds of value (%s)", valueType{kinds: kinds})
+		}
+		if names := builtRecords(rule.choices); len(names) > 1 {
+			v.report(rule.name, -1, lexer.SEVERITY_WARNING, CODE_RECORD_CONFLICT,
+				"alternatives build different records (%s)", strings.Join(names, ", "))
+		}
+	}
+	return v.diagnostics
+}
+
+type validator struct {
+	rules       map[string][]Choice
+	patterns    map[string]*regexp.Regexp
+	types       map[string]*valueType
+	diagnostics []Diagnostic
+}
+
+func (v *validator) report(rule string, choice int, severity lexer.Severity, code, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Rule:     rule,
+		Choice:   choice,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkSymbols(rule string, index int, choice Choice) {
+	for _, symbol := range choice.symbols {
+		switch symbol := symbol.(type) {
+		case RuleMatcher:
+			if _, ok := v.rules[symbol.name]; !ok {
+				v.report(rule, index, lexer.SEVERITY_ERROR, CODE_UNDEFINED_RULE,
+					"undefined rule %s", symbol.name)
+			}
+		case PatternMatcher:
+			if _, err := v.compile(symbol); err != nil {
+				v.report(rule, index, lexer.SEVERITY_ERROR, CODE_INVALID_PATTERN,
+					"invalid pattern /%s/: %s", symbol.pattern, err)
+			}
+		}
+	}
+}
+
+// Compiles the pattern as the parser does, anchored at the parse position.
+func (v *validator) compile(pattern PatternMatcher) (*regexp.Regexp, error) {
+	if re := v.patterns[pattern.pattern]; re != nil {
+		return re, nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern.pattern + `)`)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[pattern.pattern] = re
+	return re, nil
+}
+
+// Checks that each \N refers to an item of the choice, or to a subgroup of the
+// pattern when the pattern is the choice's only symbol.
+func (v *validator) checkReferences(rule string, index int, choice Choice) {
+	items := len(choice.symbols)
+	if pattern, ok := onlyPattern(choice); ok {
+		re, err := v.compile(pattern)
+		if err != nil {
+			return
+		}
+		items = re.NumSubexp()
+	}
+	visitReferences(choice.arrange, func(ref int) {
+		if ref < 0 || ref > items {
+			v.report(rule, index, lexer.SEVERITY_ERROR, CODE_REFERENCE_RANGE,
+				"reference \\%d is out of range for %d items", ref, items)
+		}
+	})
+}
+
+// Calls visit with each of the references in a projection.
+func visitReferences(arrange PostProcessing, visit func(ref int)) {
+	switch arrange := arrange.(type) {
+	case ItemProjection:
+		visit(arrange.ref)
+	case ExpandList:
+		visit(arrange.ref)
+	case PropertyGetter:
+		visitReferences(arrange.from, visit)
+	case ElementGetter:
+		visitReferences(arrange.from, visit)
+	case LengthGetter:
+		visitReferences(arrange.from, visit)
+	case ListProjection:
+		for _, value := range arrange.values {
+			visitReferences(value, visit)
+		}
+	case RecordProjection:
+		for _, attribute := range arrange.attrs {
+			switch attribute := attribute.(type) {
+			case KeyValue:
+				visitReferences(attribute.value, visit)
+			case ExpandRecord:
+				visit(attribute.ref)
+			}
+		}
+	}
+}
+
+// Checks that lists are only expanded into lists, and records into records of
+// the same name, as the evaluator requires.
+func (v *validator) checkExpansions(rule string, index int, choice Choice, arrange PostProcessing) {
+	switch arrange := arrange.(type) {
+	case ExpandList:
+		v.report(rule, index, lexer.SEVERITY_ERROR, CODE_EXPAND_NON_LIST,
+			"\\%d... can only be expanded within a list", arrange.ref)
+	case ListProjection:
+		for _, value := range arrange.values {
+			expand, ok := value.(ExpandList)
+			if !ok {
+				v.checkExpansions(rule, index, choice, value)
+				continue
+			}
+			item := v.itemType(choice, expand.ref)
+			if kinds := item.kinds & (kindString | kindRecord); kinds != 0 {
+				v.report(rule, index, lexer.SEVERITY_ERROR, CODE_EXPAND_NON_LIST,
+					"\\%d... expands a %s, not a list", expand.ref,
+					valueType{kinds, item.records})
+			}
+		}
+	case RecordProjection:
+		for _, attribute := range arrange.attrs {
+			switch attribute := attribute.(type) {
+			case KeyValue:
+				v.checkExpansions(rule, index, choice, attribute.value)
+			case ExpandRecord:
+				item := v.itemType(choice, attribute.ref)
+				if kinds := item.kinds & (kindString | kindList); kinds != 0 {
+					v.report(rule, index, lexer.SEVERITY_ERROR, CODE_EXPAND_NON_RECORD,
+						"\\%d... expands a %s into record %s", attribute.ref,
+						valueType{kinds: kinds}, arrange.name)
+				}
+				for _, name := range item.recordNames() {
+					if name != arrange.name && arrange.name != "" {
+						v.report(rule, index, lexer.SEVERITY_ERROR, CODE_RECORD_CONFLICT,
+							"\\%d... expands record %s into record %s", attribute.ref,
+							name, arrange.name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns the names of the records that the choices build, in sorted order.
+// Anonymous records and those passed through from other rules are not included.
+func builtRecords(choices []Choice) []string {
+	built := valueType{}
+	for _, choice := range choices {
+		if record, ok := choice.arrange.(RecordProjection); ok && record.name != "" {
+			built.add(valueType{kindRecord, map[string]bool{record.name: true}})
+		}
+	}
+	return built.recordNames()
+}
+
+// Returns the rules that the start rule refers to, directly or indirectly.
+func reachableRules(start string, rules map[string][]Choice) map[string]bool {
+	reachable := map[string]bool{start: true}
+	pending := []string{start}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, choice := range rules[name] {
+			for _, symbol := range choice.symbols {
+				matcher, ok := symbol.(RuleMatcher)
+				if ok && !reachable[matcher.name] {
+					reachable[matcher.name] = true
+					pending = append(pending, matcher.name)
+				}
+			}
+		}
+	}
+	return reachable
+}
+
+// Returns the rules that match some input, those with a choice made only of
+// terminals and productive rules.  References to undefined rules never match.
+func productiveRules(rules []EarleyRule) map[string]bool {
+	productive := make(map[string]bool)
+	allProductive := func(symbols []EarleySymbol) bool {
+		for _, symbol := range symbols {
+			if matcher, ok := symbol.(RuleMatcher); ok && !productive[matcher.name] {
+				return false
+			}
+		}
+		return true
+	}
+	for found := true; found; {
+		found = false
+		for _, rule := range rules {
+			if productive[rule.name] {
+				continue
+			}
+			for _, choice := range rule.choices {
+				if allProductive(choice.symbols) {
+					productive[rule.name] = true
+					found = true
+					break
+				}
+			}
+		}
+	}
+	return productive
+}
+
+// The kinds of value that a rule's alternatives may produce, as a set of bits.
+type kind uint8
+
+const (
+	kindNull kind = 1 << iota
+	kindString
+	kindList
+	kindRecord
+	// A value that is not inferred, e.g. the property of a record.
+	kindAny
+)
+
+// The values that a rule or projection may produce, with the names of records.
+type valueType struct {
+	kinds   kind
+	records map[string]bool
+}
+
+// Adds the other type's values to this one, returning whether any were new.
+func (t *valueType) add(other valueType) bool {
+	added := other.kinds&^t.kinds != 0
+	t.kinds |= other.kinds
+	for name := range other.records {
+		if !t.records[name] {
+			if t.records == nil {
+				t.records = make(map[string]bool)
+			}
+			t.records[name] = true
+			added = true
+		}
+	}
+	return added
+}
+
+func (t valueType) recordNames() []string {
+	names := make([]string, 0, len(t.records))
+	for name := range t.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describes the kinds of value, e.g. "string or record Assign".
+func (t valueType) String() string {
+	var kinds []string
+	if t.kinds&kindNull != 0 {
+		kinds = append(kinds, "null")
+	}
+	if t.kinds&kindString != 0 {
+		kinds = append(kinds, "string")
+	}
+	if t.kinds&kindList != 0 {
+		kinds = append(kinds, "list")
+	}
+	if t.kinds&kindRecord != 0 {
+		names := t.recordNames()
+		if len(names) == 0 {
+			kinds = append(kinds, "record")
+		}
+		for _, name := range names {
+			kinds = append(kinds, "record "+name)
+		}
+	}
+	if t.kinds&kindAny != 0 {
+		kinds = append(kinds, "value")
+	}
+	return strings.Join(kinds, " or ")
+}
+
+// Infers the type of each rule's value from its projections.  Rules refer to
+// each other's values, so the types are widened until none of them changes.
+func (v *validator) inferTypes(rules []EarleyRule) {
+	v.types = make(map[string]*valueType)
+	for _, rule := range rules {
+		v.types[rule.name] = &valueType{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			for _, choice := range rule.choices {
+				if v.types[rule.name].add(v.projectionType(choice, choice.arrange)) {
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) projectionType(choice Choice, arrange PostProcessing) valueType {
+	switch arrange := arrange.(type) {
+	case nil:
+		return v.itemType(choice, 0)
+	case Nothing:
+		return valueType{kinds: kindNull}
+	case StringProjection:
+		return valueType{kinds: kindString}
+	case ItemProjection:
+		return v.itemType(choice, arrange.ref)
+	case ListProjection:
+		return valueType{kinds: kindList}
+	case RecordProjection:
+		return valueType{kindRecord, map[string]bool{arrange.name: true}}
+	}
+	return valueType{kinds: kindAny}
+}
+
+// Returns the type of the value referenced by \ref in the choice.
+func (v *validator) itemType(choice Choice, ref int) valueType {
+	if _, ok := onlyPattern(choice); ok {
+		return valueType{kinds: kindString}
+	}
+	if ref == 0 {
+		return valueType{kinds: kindList}
+	}
+	if ref < 0 || ref > len(choice.symbols) {
+		return valueType{kinds: kindAny}
+	}
+	if matcher, ok := choice.symbols[ref-1].(RuleMatcher); ok {
+		if t, ok := v.types[matcher.name]; ok {
+			return *t
+		}
+		return valueType{kinds: kindAny}
+	}
+	return valueType{kinds: kindString}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/validate_test.go
+
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SymbolNotFound/ggdl/pkg/lexer"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		want    []string
+	}{
+		{"valid",
+			`list ::= "[" WORD ( "," WORD => \2 )* "]" => [\2, \3...]
+			 WORD ::= /[a-z]+/`,
+			nil},
+		{"undefined rule",
+			`a ::= "x" b | "y"`,
+			[]string{"error undefined-rule a, choice 1: undefined rule b"}},
+		{"unreachable rule",
+			`a ::= "x"
+			 b ::= "y"`,
+			[]string{"warning unreachable-rule b: rule is not reachable from start rule a"}},
+		{"unproductive rules",
+			`a ::= "x" | b
+			 b ::= "(" c ")"
+			 c ::= b`,
+			[]string{
+				"error unproductive-rule b: rule cannot match any input",
+				"error unproductive-rule c: rule cannot match any input",
+			}},
+		{"invalid pattern",
+			`a ::= /x{2,1}/`,
+			[]string{"error invalid-pattern a, choice 1: invalid pattern /x{2,1}/: " +
+				"error parsing regexp: invalid repeat count: `{2,1}`"}},
+		{"reference out of range",
+			`a ::= "x" "y" => [\1, \3]`,
+			[]string{"error reference-range a, choice 1: reference \\3 is out of range for 2 items"}},
+		{"pattern subgroup out of range",
+			`a ::= /(x)y/ => \2`,
+			[]string{"error reference-range a, choice 1: reference \\2 is out of range for 1 items"}},
+		{"expand string",
+			`a ::= "x" => [\1...]`,
+			[]string{"error expand-non-list a, choice 1: \\1... expands a string, not a list"}},
+		{"expand alternative records",
+			`a ::= b => [\1...]
+			 b ::= "x" => [\1] | "y" => Y{ k: \1 }`,
+			[]string{"error expand-non-list a, choice 1: \\1... expands a record Y, not a list",
+				"warning kind-conflict b: alternatives produce different kinds of value (list or record)"}},
+		{"expand optional list",
+			`a ::= b? => [\1...]
+			 b ::= "x" => [\1]`,
+			nil},
+		{"expand list into record",
+			`a ::= b => R{ \1... }
+			 b ::= "x" => [\1]`,
+			[]string{"error expand-non-record a, choice 1: \\1... expands a list into record R"}},
+		{"record conflict",
+			`a ::= b => R{ \1..., key: "k" }
+			 b ::= "x" => R{ k: \1 } | "y" => S{ k: \1 }`,
+			[]string{"error record-conflict a, choice 1: \\1... expands record S into record R",
+				"warning record-conflict b: alternatives build different records (R, S)"}},
+		{"expand into anonymous record",
+			`a ::= b => { \1..., key: "k" }
+			 b ::= "x" => R{ k: \1 } | "y" => { k: \1 }`,
+			nil},
+		{"alternative records",
+			`a ::= "x" => Foo{ k: \1 } | "y" => Bar{ k: \1 } | "z" => Foo{}`,
+			[]string{"warning record-conflict a: alternatives build different records (Bar, Foo)"}},
+		{"list or null",
+			`a ::= "x" => [\1] | "y" | b
+			 b ::= "z"? => \1`,
+			nil},
+		{"string and list",
+			`a ::= "x" => \1 | "y"`,
+			[]string{"warning kind-conflict a: alternatives produce different kinds of value (string or list)"}},
+		{"helper rules",
+			`a ::= ( b "x" => \3 )?`,
+			[]string{
+				"error undefined-rule a$2, choice 1: undefined rule b",
+				"error reference-range a$2, choice 1: reference \\3 is out of range for 2 items",
+				"error unproductive-rule a$2: rule cannot match any input",
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := LoadGrammar(strings.NewReader(tt.grammar))
+			if err != nil {
+				t.Fatalf("LoadGrammar() error = %v", err)
+			}
+			var got []string
+			for _, diag := range Validate(g) {
+				got = append(got, fmt.Sprintf("%s %s %s", diag.Severity, diag.Code, diag))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate()\n  got %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_EarleyBNF(t *testing.T) {
+	// The bootstrap grammar's only warnings are for the rules whose alternatives
+	// are told apart by the names of their records.
+	var records []string
+	for _, diag := range Validate(EarleyBNFGrammar()) {
+		if diag.Code != CODE_RECORD_CONFLICT || diag.Severity != lexer.SEVERITY_WARNING {
+			t.Errorf("Validate(EarleyBNFGrammar()) %s", diag)
+		}
+		records = append(records, diag.Rule)
+	}
+	want := []string{"production", "rule_atom", "rule_matcher", "postproc_atom",
+		"postproc_prop", "postproc_kv"}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Validate(EarleyBNFGrammar()) record conflicts = %q, want %q", records, want)
+	}
+	if diags := Validate(NewGrammar()); len(diags) != 1 || diags[0].Code != CODE_NO_RULES {
+		t.Errorf("Validate(NewGrammar()) = %v", diags)
+	}
+}
+
+func TestValidate_ExpandOutsideList(t *testing.T) {
+	g := testGrammar(
+		RuleSpec{"a", spec(s{"b"}), rproj("R", kv{"key", ExpandList{first}})},
+		RuleSpec{"b", spec(l{"x"}), lproj(first)},
+	)
+	want := `a, choice 1: \1... can only be expanded within a list`
+	if diags := Validate(g); len(diags) != 1 || diags[0].Error() != want {
+		t.Errorf("Validate() = %v, want %s", diags, want)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	g, err := LoadGrammar(strings.NewReader(
+		`top ::= opt "x" | list
+		 opt ::= "a"? => \1
+		 list ::= opt opt WORD* ";"
+		 WORD ::= /[a-z]+/`))
+	if err != nil {
+		t.Fatalf("LoadGrammar() error = %v", err)
+	}
+	analysis := Analyze(g)
+	nullable := map[string]bool{"opt": true, "opt$1": true, "list$1": true}
+	if !reflect.DeepEqual(analysis.Nullable, nullable) {
+		t.Errorf("Nullable = %v, want %v", analysis.Nullable, nullable)
+	}
+	first := map[string]string{
+		"top":    `[";" "a" "x" /[a-z]+/]`,
+		"opt":    `["a"]`,
+		"list":   `[";" "a" /[a-z]+/]`,
+		"list$1": `[/[a-z]+/]`,
+		"WORD":   `[/[a-z]+/]`,
+	}
+	for rule, want := range first {
+		if got := fmt.Sprint(analysis.First[rule]); got != want {
+			t.Errorf("First[%s] = %s, want %s", rule, got, want)
+		}
+	}
+}
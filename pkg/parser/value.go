@@ -0,0 +1,120 @@
+// Copyright (c) 2023 Symbol Not Found L.L.C.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// github:SymbolNotFound/ggdl/go/parser/value.go
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Value is the result of post-processing a parse tree, and is one of nil (for
+// a rule that projects Nothing), a string, a List or a *Record, or a bool or int
+// for the value of a BooleanProjection or LengthGetter.
+type Value = any
+
+// A list of values, e.g. the values of a choice's symbols or a ListProjection.
+type List []Value
+
+func (list List) String() string { return formatValue(list) }
+
+// A named record of attributes, constructed by a RecordProjection.  Attributes
+// are kept in the order they were first set.
+type Record struct {
+	Name   string
+	keys   []string
+	values map[string]Value
+}
+
+func NewRecord(name string) *Record {
+	return &Record{Name: name, values: make(map[string]Value)}
+}
+
+// Returns the value of the named attribute and whether the record has it.
+func (record *Record) Get(key string) (Value, bool) {
+	value, ok := record.values[key]
+	return value, ok
+}
+
+// Sets the value of the named attribute, replacing any earlier value.
+func (record *Record) Set(key string, value Value) {
+	if _, ok := record.values[key]; !ok {
+		record.keys = append(record.keys, key)
+	}
+	record.values[key] = value
+}
+
+// Returns the names of the record's attributes.
+func (record *Record) Keys() []string { return record.keys }
+
+func (record *Record) String() string { return formatValue(record) }
+
+// Formats a value with strings quoted, lists in [brackets] and records in the
+// same Name{key: value} notation as their projections.
+func formatValue(value Value) string {
+	var text strings.Builder
+	writeValue(&text, value)
+	return text.String()
+}
+
+func writeValue(text *strings.Builder, value Value) {
+	switch value := value.(type) {
+	case nil:
+		text.WriteString("null")
+	case string:
+		fmt.Fprintf(text, "%q", value)
+	case List:
+		text.WriteString("[")
+		for i, item := range value {
+			if i > 0 {
+				text.WriteString(", ")
+			}
+			writeValue(text, item)
+		}
+		text.WriteString("]")
+	case *Record:
+		text.WriteString(value.Name + "{")
+		for i, key := range value.keys {
+			if i > 0 {
+				text.WriteString(", ")
+			}
+			text.WriteString(key + ": ")
+			writeValue(text, value.values[key])
+		}
+		text.WriteString("}")
+	default:
+		fmt.Fprintf(text, "%v", value)
+	}
+}
+
+// Describes the type of a value for error messages.
+func describeValue(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int:
+		return "number"
+	case string:
+		return "string"
+	case List:
+		return "list"
+	case *Record:
+		return "record " + value.Name
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,7 @@
+module github.com/SymbolNotFound/ggdl/tests
+
+go 1.23
+
+require github.com/SymbolNotFound/ggdl/pkg v0.0.0
+
+replace github.com/SymbolNotFound/ggdl/pkg => ../pkg
@@ -5,7 +5,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/SymbolNotFound/ggdl/go/lexer"
+	"github.com/SymbolNotFound/ggdl/pkg/lexer"
 )
 
 func TestTokenize(t *testing.T) {
@@ -17,23 +17,18 @@ func TestTokenize(t *testing.T) {
 		lexer.LeftDoubleArrow(lexer.NewTokenPos(1, 2).InSentence()),
 		lexer.ExpressionStart(lexer.NewTokenPos(1, 5).InSentence()),
 		lexer.KeywordAt("next", lexer.NewTokenPos(1, 6).InSentence()),
-		lexer.QuestionMark(lexer.NewTokenPos(1, 11).InSentence()),
-		lexer.Identifier("x", lexer.NewTokenPos(1, 12).InSentence()),
+		lexer.Variable("?x", lexer.NewTokenPos(1, 11).InSentence()),
 		lexer.Identifier("fun", lexer.NewTokenPos(1, 14).InSentence()),
 		lexer.ExpressionEnd(lexer.NewTokenPos(1, 17).InSentence()),
 		lexer.ExpressionStart(lexer.NewTokenPos(2, 3).InSentence()),
 		lexer.KeywordAt("does", lexer.NewTokenPos(2, 4).InSentence()),
-		lexer.QuestionMark(lexer.NewTokenPos(2, 9).InSentence()),
-		lexer.Identifier("x", lexer.NewTokenPos(2, 10).InSentence()),
-		lexer.QuestionMark(lexer.NewTokenPos(2, 12).InSentence()),
-		lexer.Identifier("y", lexer.NewTokenPos(2, 13).InSentence()),
+		lexer.Variable("?x", lexer.NewTokenPos(2, 9).InSentence()),
+		lexer.Variable("?y", lexer.NewTokenPos(2, 12).InSentence()),
 		lexer.ExpressionEnd(lexer.NewTokenPos(2, 14).InSentence()),
 		lexer.ExpressionStart(lexer.NewTokenPos(2, 16).InSentence()),
 		lexer.Identifier("fun", lexer.NewTokenPos(2, 17).InSentence()),
-		lexer.QuestionMark(lexer.NewTokenPos(2, 21).InSentence()),
-		lexer.Identifier("p", lexer.NewTokenPos(2, 22).InSentence()),
-		lexer.QuestionMark(lexer.NewTokenPos(2, 24).InSentence()),
-		lexer.Identifier("y", lexer.NewTokenPos(2, 25).InSentence()),
+		lexer.Variable("?p", lexer.NewTokenPos(2, 21).InSentence()),
+		lexer.Variable("?y", lexer.NewTokenPos(2, 24).InSentence()),
 		lexer.ExpressionEnd(lexer.NewTokenPos(2, 26).InSentence()),
 		lexer.ExpressionEnd(lexer.NewTokenPos(2, 27).InSentence()),
 		lexer.LineComment(";; does arity oddity \"intended",
@@ -60,6 +55,9 @@ func TestTokenize(t *testing.T) {
 		if tt.Line() != tokens[i].Line() || tt.Column() != tokens[i].Column() {
 			t.Fatalf("token position mismatch %d, %d expected %d, %d", tokens[i].Line(), tokens[i].Column(), tt.Line(), tt.Column())
 		}
+		if tt.TokenPos != tokens[i].TokenPos {
+			t.Fatalf("token flag mismatch %s expected %s", tokens[i].TokenPos, tt.TokenPos)
+		}
 	}
 }
 
@@ -69,17 +67,20 @@ func tokenizeString(input string) []lexer.Token {
 
 	reader := lexer.NewTokenReader(stringReader, output)
 	tokens := make([]lexer.Token, 0, 32)
+	done := make(chan bool)
 	go func() {
 		for token := range reader.TokenReceiver() {
 			tokens = append(tokens, token)
 			fmt.Println(token)
 		}
+		close(done)
 	}()
 
 	err := lexer.ReadAll(reader)
 	if err != nil {
 		panic(err)
 	}
+	<-done
 	return tokens
 }
 
@@ -103,14 +104,17 @@ func TestTokenize_Errors(t *testing.T) {
 	output := make(chan lexer.Token)
 	reader := lexer.NewTokenReader(br, output)
 	tokens := make([]lexer.Token, 0, 32)
+	done := make(chan bool)
 	go func() {
 		for token := range reader.TokenReceiver() {
 			tokens = append(tokens, token)
 			fmt.Println(token)
 		}
+		close(done)
 	}()
 
 	err := lexer.ReadAll(reader)
+	<-done
 	if err == nil {
 		t.Errorf("Reader that returns nil should route its error through ReadAll")
 	}